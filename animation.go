@@ -0,0 +1,175 @@
+package raycaster
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Animation loop modes
+const (
+	LoopRepeat   = "repeat"   // back to the first frame after the last one
+	LoopPingPong = "pingpong" // back to the first frame the way it came and then forwards again
+	LoopOnce     = "once"     // stays on the last frame
+)
+
+// DefaultFrameRate is the frames per second of animations that don't set one
+const DefaultFrameRate = 8
+
+// Animation is a texture made of a sequence of images. Any images named like water_0, water_1 etc.
+// are an animation called water. A JSON file with the same name in the images directory
+// (e.g. water.json) can change how it plays or list the frames itself:
+//
+//	{ "fps": 12, "loop": "pingpong", "frames": ["panel_a", "panel_b"] }
+//
+// The animation can be used anywhere a texture can by its name.
+type Animation struct {
+	FrameRate float64  `json:"fps"`
+	Loop      string   `json:"loop"`
+	Frames    []string `json:"frames"` // names of the images. Defaults to the numbered images.
+
+	frames []*image.NRGBA
+}
+
+// Frame returns the frame to show t seconds after the animation started
+func (a *Animation) Frame(t float64) *image.NRGBA {
+	n := len(a.frames)
+	i := int(t * a.FrameRate)
+	switch a.Loop {
+	case LoopOnce:
+		i = minInt(i, n-1)
+	case LoopPingPong:
+		if n == 1 {
+			return a.frames[0]
+		}
+		// 0 1 2 3 2 1 0 1 ... goes through all of them twice except for the first and the last
+		period := 2*n - 2
+		i %= period
+		if i >= n {
+			i = period - i
+		}
+	default:
+		i %= n
+	}
+	return a.frames[i]
+}
+
+// updateAnimations puts the frame every animation is on in the textures under the same name
+// so it's drawn like any other texture. It's called once every frame before drawing.
+func (e *Engine) updateAnimations() {
+	for name, a := range e.animations {
+		e.textures[name] = a.Frame(e.Time)
+	}
+}
+
+// sequences finds the images that are numbered frames of an animation e.g. water_0, water_1.
+// The numbers have to start at 0 and can't skip any. Returns the names of the frames by
+// animation name. Names that are already an image on their own aren't animations.
+func sequences(names []string) map[string][]string {
+	numbered := map[string]map[int]string{}
+	isName := map[string]bool{}
+	for _, name := range names {
+		isName[name] = true
+		sep := strings.LastIndex(name, "_")
+		if sep <= 0 {
+			continue
+		}
+		n, err := strconv.Atoi(name[sep+1:])
+		if err != nil || n < 0 {
+			continue
+		}
+		base := name[:sep]
+		if numbered[base] == nil {
+			numbered[base] = map[int]string{}
+		}
+		numbered[base][n] = name
+	}
+
+	found := map[string][]string{}
+	for base, frames := range numbered {
+		if isName[base] {
+			continue
+		}
+		var sequence []string
+		for n := 0; frames[n] != ""; n++ {
+			sequence = append(sequence, frames[n])
+		}
+		if len(sequence) > 1 {
+			found[base] = sequence
+		}
+	}
+	return found
+}
+
+// loadAnimations sets up the animations from the numbered images in the textures and the
+// descriptors (JSON files) in the images directory
+func (e *Engine) loadAnimations(imageDir string, descriptors []string) error {
+	names := make([]string, 0, len(e.textures))
+	for name := range e.textures {
+		names = append(names, name)
+	}
+
+	e.animations = map[string]*Animation{}
+	for name, frames := range sequences(names) {
+		e.animations[name] = &Animation{Frames: frames}
+	}
+
+	for _, name := range descriptors {
+		a := &Animation{}
+		if seq, ok := e.animations[name]; ok {
+			a.Frames = seq.Frames
+		}
+		if err := readDescriptor(path.Join(imageDir, name+".json"), a); err != nil {
+			return err
+		}
+		if len(a.Frames) == 0 {
+			return fmt.Errorf("animation %q doesn't have any frames", name)
+		}
+		e.animations[name] = a
+	}
+
+	for name, a := range e.animations {
+		if a.FrameRate <= 0 {
+			a.FrameRate = DefaultFrameRate
+		}
+		switch a.Loop {
+		case "":
+			a.Loop = LoopRepeat
+		case LoopRepeat, LoopPingPong, LoopOnce:
+		default:
+			return fmt.Errorf("animation %q: unknown loop %q. Use %s, %s or %s", name, a.Loop, LoopRepeat, LoopPingPong, LoopOnce)
+		}
+
+		a.frames = a.frames[:0]
+		for _, frame := range a.Frames {
+			img, ok := e.textures[frame]
+			if !ok {
+				return fmt.Errorf("animation %q: frame %q not found", name, frame)
+			}
+			a.frames = append(a.frames, img)
+		}
+	}
+
+	e.updateAnimations()
+	return nil
+}
+
+// readDescriptor reads the animation descriptor into a. Anything that isn't in the file is left as it is.
+func readDescriptor(filename string, a *Animation) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	d := json.NewDecoder(f)
+	d.DisallowUnknownFields()
+	if err := d.Decode(a); err != nil {
+		return fmt.Errorf("couldn't read animation %s: %s", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package raycaster
+
+import (
+	"fmt"
+	"image"
+	"testing"
+)
+
+func TestAnimationFrame(t *testing.T) {
+	frames := []*image.NRGBA{image.NewNRGBA(image.Rect(0, 0, 1, 1)), image.NewNRGBA(image.Rect(0, 0, 1, 1)), image.NewNRGBA(image.Rect(0, 0, 1, 1))}
+	tests := []struct {
+		loop     string
+		expected []int // frame for every second
+	}{
+		{LoopRepeat, []int{0, 1, 2, 0, 1, 2}},
+		{LoopPingPong, []int{0, 1, 2, 1, 0, 1}},
+		{LoopOnce, []int{0, 1, 2, 2, 2, 2}},
+	}
+
+	for _, test := range tests {
+		a := &Animation{FrameRate: 1, Loop: test.loop, frames: frames}
+		for second, expected := range test.expected {
+			if a.Frame(float64(second)+0.5) != frames[expected] {
+				t.Errorf("%s after %d seconds: Expected frame %d", test.loop, second, expected)
+			}
+		}
+	}
+}
+
+func TestSequences(t *testing.T) {
+	names := []string{"water_1", "water_0", "water_2", "wood", "lamp_0", "gap_0", "gap_2", "stone", "stone_0", "stone_1", "red_brick"}
+	expected := map[string][]string{"water": {"water_0", "water_1", "water_2"}}
+
+	// a single frame, a gap in the numbers, an image that already has the name or an underscore in the name aren't animations
+	if found := sequences(names); fmt.Sprint(found) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v. Received: %v", expected, found)
+	}
+}
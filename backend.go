@@ -0,0 +1,75 @@
+package raycaster
+
+import (
+	"image/color"
+
+	"github.com/kyriacos/colorbuffer"
+)
+
+// Canvas is what the minimap is drawn on. Coordinates are in window pixels.
+type Canvas interface {
+	FillRect(x, y, w, h int, c color.NRGBA)
+	DrawLine(x1, y1, x2, y2 int, c color.NRGBA)
+}
+
+// Backend is everything the game loop needs from the outside world: a window to show the frames in,
+// the input and a clock. SDL is one of them (see the sdlbackend package) and Headless is another
+// one that doesn't need a display at all.
+type Backend interface {
+	Canvas
+
+	// Open creates the window. The frames are frameWidth x frameHeight and are stretched to the window.
+	// If it fails it cleans up after itself so there's nothing to Close.
+	Open(title string, width, height, frameWidth, frameHeight int) error
+	Close()
+
+	// PollEvent returns the next pending input event. ok is false when there are no more events for this frame.
+	PollEvent() (event Event, ok bool)
+
+	// DrawFrame draws the frame over the whole window. Anything drawn on the Canvas after it is on top of it.
+	DrawFrame(frame *colorbuffer.ColorBuffer)
+	// Present shows everything drawn since the last Present
+	Present()
+
+	Now() float64          // seconds since some point in the past. Only the difference between two calls matters
+	Delay(seconds float64) // pause the game loop
+}
+
+// EventType is the kind of input event
+type EventType int
+
+// All the input events the game cares about
+const (
+	QuitEvent        EventType = iota // the window was closed
+	KeyDownEvent                      // Key was pressed
+	KeyUpEvent                        // Key was released
+	MouseMotionEvent                  // the mouse moved YRel pixels
+)
+
+// Key is a key on the keyboard. Only the keys the game uses are here.
+type Key int
+
+// The keys the game uses
+const (
+	KeyUnknown Key = iota
+	KeyEscape
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeySpace
+	KeyPageUp
+	KeyPageDown
+	KeyEnd
+	KeyC
+	KeyG
+	KeyJ
+	KeyT
+)
+
+// Event is a single input event from the backend
+type Event struct {
+	Type EventType
+	Key  Key // for KeyDownEvent and KeyUpEvent
+	YRel int // for MouseMotionEvent. How far the mouse moved down (+) or up (-)
+}
@@ -0,0 +1,108 @@
+// Command raycaster runs the raycaster in an SDL window
+package main
+
+import (
+	"flag"
+	"log"
+	"strings"
+
+	raycaster "github.com/kyriacos/go-raycaster"
+	"github.com/kyriacos/go-raycaster/sdlbackend"
+)
+
+var (
+	showFPS         = flag.Bool("showFPS", false, "Show current FPS and on exit display the average FPS.")
+	textureSampling = flag.String("sampling", raycaster.SamplingNearest, "Texture sampling: nearest, bilinear or mipmap.")
+	resolution      = flag.String("resolution", raycaster.ResolutionNative, "Render resolution e.g. 320x200, 640x400 or native for the size of the window.")
+	scaling         = flag.String("scaling", sdlbackend.ScalingNearest, "How the render resolution is scaled to the window: nearest or linear.")
+
+	// everything in the config file can be overridden here. The flags have the same names as the keys.
+	configFile   = flag.String("config", "", "Config file. Defaults to the first one of "+strings.Join(raycaster.ConfigPaths(), ", ")+" that exists.")
+	fov          = flag.Float64("fov", raycaster.FOV*180/raycaster.PI, "Field of view in degrees.")
+	windowWidth  = flag.Int("windowWidth", raycaster.WindowWidth, "Width of the window.")
+	windowHeight = flag.Int("windowHeight", raycaster.WindowHeight, "Height of the window.")
+	walkSpeed    = flag.Float64("walkSpeed", raycaster.WalkSpeed, "How fast the player walks (pixels per second).")
+	turnSpeed    = flag.Float64("turnSpeed", raycaster.TurnSpeed, "How fast the player turns (degrees per second).")
+	level        = flag.String("level", raycaster.DefaultLevel, "The level file.")
+	textures     = flag.String("textures", raycaster.DefaultImageDir, "Directory with all the textures.")
+	minimapScale = flag.Float64("minimapScale", raycaster.MinimapScaleFactor, "Size of the minimap compared to the map.")
+)
+
+// loadConfig reads the config file if there is one and then applies the flags that were set on top of it.
+// Anything that is still empty gets its default from the engine.
+func loadConfig() (raycaster.Config, error) {
+	var cfg raycaster.Config
+
+	path := *configFile
+	if path == "" {
+		path = raycaster.FindConfig()
+	}
+	if path != "" {
+		if err := raycaster.LoadConfig(path, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "fov":
+			cfg.FOV = fov
+		case "windowWidth":
+			cfg.WindowWidth = windowWidth
+		case "windowHeight":
+			cfg.WindowHeight = windowHeight
+		case "walkSpeed":
+			cfg.WalkSpeed = walkSpeed
+		case "turnSpeed":
+			cfg.TurnSpeed = turnSpeed
+		case "level":
+			cfg.Level = *level
+		case "textures":
+			cfg.ImageDir = *textures
+		case "minimapScale":
+			cfg.MinimapScale = minimapScale
+		}
+	})
+
+	// the window size is needed for the resolution so it can't wait for the engine's defaults
+	if cfg.WindowWidth == nil {
+		cfg.WindowWidth = raycaster.Int(raycaster.WindowWidth)
+	}
+	if cfg.WindowHeight == nil {
+		cfg.WindowHeight = raycaster.Int(raycaster.WindowHeight)
+	}
+	width, height, err := raycaster.ParseResolution(*resolution, *cfg.WindowWidth, *cfg.WindowHeight)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Width, cfg.Height = width, height
+	cfg.Sampling = *textureSampling
+
+	return cfg, nil
+}
+
+func main() {
+	flag.Parse()
+
+	backend, err := sdlbackend.New(*scaling)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	engine, err := raycaster.New(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, w := range engine.Warnings() {
+		log.Print(w)
+	}
+
+	if err := engine.Run(backend, *showFPS); err != nil {
+		log.Fatal(err)
+	}
+}
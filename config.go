@@ -0,0 +1,207 @@
+package raycaster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Where the textures and the first level are loaded from by default. Both are relative to the working directory.
+const (
+	DefaultImageDir = "./images/"
+	DefaultLevel    = "./levels/level1.json"
+)
+
+// ConfigFile is the name of the config file. See ConfigPaths for where it's looked for.
+const ConfigFile = "raycaster.json"
+
+// Config is how an engine is set up. Anything left empty gets its default.
+// Everything with a key can also be set in the config file e.g. {"fov": 90, "level": "./levels/maze.json"}
+//
+// The numbers are pointers so a 0 that was set (e.g. a walkSpeed of 0 to stand still) isn't mistaken
+// for one that was left out. Use Int and Float64 to set them.
+type Config struct {
+	ImageDir string `json:"textures"` // directory with all the textures. Defaults to DefaultImageDir
+	Level    string `json:"level"`    // the level file. Defaults to DefaultLevel
+
+	WindowWidth  *int `json:"windowWidth"`  // Defaults to WindowWidth
+	WindowHeight *int `json:"windowHeight"` // Defaults to WindowHeight
+
+	// Width and Height are the render resolution. Both default to the size of the window. See ParseResolution.
+	Width, Height int `json:"-"`
+
+	FOV          *float64 `json:"fov"`          // field of view in degrees. Defaults to 60
+	WalkSpeed    *float64 `json:"walkSpeed"`    // pixels per second. Defaults to WalkSpeed
+	TurnSpeed    *float64 `json:"turnSpeed"`    // degrees per second. Defaults to TurnSpeed
+	MinimapScale *float64 `json:"minimapScale"` // size of the minimap compared to the map. 0 hides it. Defaults to MinimapScaleFactor
+
+	Sampling string `json:"-"` // texture sampling. Defaults to SamplingNearest
+}
+
+// Int returns a pointer to v for the Config fields that are optional
+func Int(v int) *int {
+	return &v
+}
+
+// Float64 returns a pointer to v for the Config fields that are optional
+func Float64(v float64) *float64 {
+	return &v
+}
+
+// withDefaults fills in everything that was left empty. Whatever was set is left alone even if it's 0
+// so validate can tell if it's wrong.
+func (c Config) withDefaults() Config {
+	if c.ImageDir == "" {
+		c.ImageDir = DefaultImageDir
+	}
+	if c.Level == "" {
+		c.Level = DefaultLevel
+	}
+	if c.WindowWidth == nil {
+		c.WindowWidth = Int(WindowWidth)
+	}
+	if c.WindowHeight == nil {
+		c.WindowHeight = Int(WindowHeight)
+	}
+	if c.Width == 0 && c.Height == 0 {
+		c.Width, c.Height = *c.WindowWidth, *c.WindowHeight
+	}
+	if c.FOV == nil {
+		c.FOV = Float64(FOV * 180 / PI)
+	}
+	if c.WalkSpeed == nil {
+		c.WalkSpeed = Float64(WalkSpeed)
+	}
+	if c.TurnSpeed == nil {
+		c.TurnSpeed = Float64(TurnSpeed)
+	}
+	if c.MinimapScale == nil {
+		c.MinimapScale = Float64(MinimapScaleFactor)
+	}
+	if c.Sampling == "" {
+		c.Sampling = SamplingNearest
+	}
+	return c
+}
+
+// validate returns an error for the first setting that can't be used. The defaults have to be filled in first.
+func (c Config) validate() error {
+	switch {
+	case *c.WindowWidth <= 0 || *c.WindowHeight <= 0:
+		return fmt.Errorf("invalid window size %dx%d", *c.WindowWidth, *c.WindowHeight)
+	case c.Width <= 0 || c.Height <= 0:
+		return fmt.Errorf("invalid resolution %dx%d", c.Width, c.Height)
+	case *c.FOV <= 0 || *c.FOV >= 180:
+		return fmt.Errorf("invalid fov %g. It has to be more than 0 and less than 180 degrees", *c.FOV)
+	case *c.WalkSpeed < 0:
+		return fmt.Errorf("invalid walkSpeed %g. It can't be negative", *c.WalkSpeed)
+	case *c.TurnSpeed < 0:
+		return fmt.Errorf("invalid turnSpeed %g. It can't be negative", *c.TurnSpeed)
+	case *c.MinimapScale < 0:
+		return fmt.Errorf("invalid minimapScale %g. It can't be negative", *c.MinimapScale)
+	}
+	return validSampling(c.Sampling)
+}
+
+// ConfigPaths returns where the config file is looked for in order. First the working directory
+// and then the user's config directory e.g. ~/.config/raycaster/raycaster.json on Linux.
+func ConfigPaths() []string {
+	paths := []string{ConfigFile}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "raycaster", ConfigFile))
+	}
+	return paths
+}
+
+// FindConfig returns the first config file in ConfigPaths that exists or "" if there isn't one
+func FindConfig() string {
+	for _, path := range ConfigPaths() {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// LoadConfig reads the config file into cfg. Only the keys in the file are changed so cfg can
+// already have the defaults in it. Unknown keys are an error so typos don't go unnoticed.
+func LoadConfig(path string, cfg *Config) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to load config file: %s. Error: %s", path, err)
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return fmt.Errorf("couldn't load config: %s. Error: %s", path, err)
+	}
+	for key := range keys {
+		if err := checkConfigKey(key); err != nil {
+			return fmt.Errorf("invalid config: %s. Error: %s", path, err)
+		}
+	}
+
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return fmt.Errorf("couldn't load config: %s. Error: %s", path, err)
+	}
+	return nil
+}
+
+// ConfigKeys returns all the keys that can be in the config file sorted by name
+func ConfigKeys() []string {
+	// every field that has a key ends up in the JSON
+	b, _ := json.Marshal(Config{})
+	var fields map[string]interface{}
+	json.Unmarshal(b, &fields)
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkConfigKey returns an error if the key isn't one of ConfigKeys. If it looks like a typo
+// of one of them the error says which one.
+func checkConfigKey(key string) error {
+	keys := ConfigKeys()
+	closest, distance := "", 3 // anything further away than 2 edits isn't a typo
+	for _, k := range keys {
+		if k == key {
+			return nil
+		}
+		if d := editDistance(strings.ToLower(key), strings.ToLower(k)); d < distance {
+			closest, distance = k, d
+		}
+	}
+	if closest != "" {
+		return fmt.Errorf("unknown key %q. Did you mean %q?", key, closest)
+	}
+	return fmt.Errorf("unknown key %q. Use one of: %s", key, strings.Join(keys, ", "))
+}
+
+// editDistance is the number of single letter changes needed to turn a into b (Levenshtein)
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(minInt(prev[j]+1, current[j-1]+1), prev[j-1]+cost)
+		}
+		prev = current
+	}
+	return prev[len(b)]
+}
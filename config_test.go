@@ -0,0 +1,143 @@
+package raycaster
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes the config to a temporary file and returns its path
+func writeConfig(t *testing.T, config string) string {
+	f, err := ioutil.TempFile("", "raycaster*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(config); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, `{"fov": 90, "level": "./levels/maze.json", "windowWidth": 640, "windowHeight": 400}`)
+	defer os.Remove(path)
+
+	cfg := Config{WalkSpeed: Float64(50), FOV: Float64(75)}
+	if err := LoadConfig(path, &cfg); err != nil {
+		t.Fatalf("Failed to load the config: %s", err)
+	}
+	if *cfg.FOV != 90 || cfg.Level != "./levels/maze.json" || *cfg.WindowWidth != 640 || *cfg.WindowHeight != 400 {
+		t.Errorf("The keys in the file should be loaded. Received: %+v", cfg)
+	}
+	if *cfg.WalkSpeed != 50 || cfg.TurnSpeed != nil {
+		t.Errorf("Keys that aren't in the file should be left alone. Received: %+v", cfg)
+	}
+}
+
+func TestLoadConfigUnknownKeys(t *testing.T) {
+	for config, want := range map[string]string{
+		`{"fvo": 90}`:                `unknown key "fvo". Did you mean "fov"?`,
+		`{"FOV": 90}`:                `unknown key "FOV". Did you mean "fov"?`,
+		`{"walk_speed": 10}`:         `unknown key "walk_speed". Did you mean "walkSpeed"?`,
+		`{"fov": 90, "colour": "1"}`: `unknown key "colour". Use one of: fov, level, minimapScale, textures, turnSpeed, walkSpeed, windowHeight, windowWidth`,
+		`{"fov": "wide"}`:            `cannot unmarshal string`,
+	} {
+		path := writeConfig(t, config)
+		defer os.Remove(path)
+
+		err := LoadConfig(path, &Config{})
+		if err == nil || !strings.Contains(err.Error(), want) || !strings.Contains(err.Error(), path) {
+			t.Errorf("%s: expected an error about %q. Received: %v", config, want, err)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := (Config{}).withDefaults().validate(); err != nil {
+		t.Errorf("The defaults should be valid. Received: %s", err)
+	}
+	for _, cfg := range []Config{
+		{FOV: Float64(180)},
+		{FOV: Float64(-10)},
+		{FOV: Float64(0)},
+		{WalkSpeed: Float64(-1)},
+		{MinimapScale: Float64(-0.2)},
+		{WindowWidth: Int(-640), WindowHeight: Int(400)},
+		{WindowWidth: Int(0)},
+		{Width: 320},
+		{Sampling: "trilinear"},
+	} {
+		if err := cfg.withDefaults().validate(); err == nil {
+			t.Errorf("%+v should be invalid", cfg)
+		}
+	}
+}
+
+func TestConfigKeepsExplicitZeros(t *testing.T) {
+	path := writeConfig(t, `{"walkSpeed": 0, "turnSpeed": 0, "minimapScale": 0}`)
+	defer os.Remove(path)
+
+	var cfg Config
+	if err := LoadConfig(path, &cfg); err != nil {
+		t.Fatalf("Failed to load the config: %s", err)
+	}
+	e, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Zero speeds and no minimap should be valid. Received: %s", err)
+	}
+	if e.Player.walkSpeed != 0 || e.Player.turnSpeed != 0 || e.minimapScale != 0 {
+		t.Errorf("The zeros should be kept. Received: %f, %f, %f", e.Player.walkSpeed, e.Player.turnSpeed, e.minimapScale)
+	}
+
+	// without a minimap there's nothing to draw
+	h := NewHeadless(1)
+	h.Open("", 64, 40, 64, 40)
+	e.RenderMinimap(h)
+	for i := 0; i < len(h.Screen.Pixels); i += 4 {
+		if c := h.Screen.Pixels[i : i+3]; c[0] != 0 || c[1] != 0 || c[2] != 0 {
+			t.Fatal("Nothing should be drawn without a minimap")
+		}
+	}
+
+	// zeros that can't be used are errors instead of getting the default
+	for _, config := range []string{`{"fov": 0}`, `{"windowWidth": 0}`, `{"windowHeight": 0}`} {
+		path := writeConfig(t, config)
+		defer os.Remove(path)
+
+		var cfg Config
+		if err := LoadConfig(path, &cfg); err != nil {
+			t.Fatalf("Failed to load the config: %s", err)
+		}
+		if _, err := New(cfg); err == nil {
+			t.Errorf("%s should be invalid", config)
+		}
+	}
+}
+
+func TestConfigDefaultsEachWindowDimension(t *testing.T) {
+	if cfg := (Config{WindowWidth: Int(640)}).withDefaults(); *cfg.WindowWidth != 640 || *cfg.WindowHeight != WindowHeight {
+		t.Errorf("Only the height should get its default. Received: %dx%d", *cfg.WindowWidth, *cfg.WindowHeight)
+	}
+	if cfg := (Config{WindowHeight: Int(400)}).withDefaults(); *cfg.WindowWidth != WindowWidth || *cfg.WindowHeight != 400 {
+		t.Errorf("Only the width should get its default. Received: %dx%d", *cfg.WindowWidth, *cfg.WindowHeight)
+	}
+}
+
+func TestEngineUsesTheConfig(t *testing.T) {
+	e, err := New(Config{WindowWidth: Int(640), WindowHeight: Int(400), FOV: Float64(90), TurnSpeed: Float64(180)})
+	if err != nil {
+		t.Fatalf("Failed to create the engine: %s", err)
+	}
+	if e.Frame.Width != 640 || e.Frame.Height != 400 {
+		t.Errorf("The render resolution should default to the window. Received: %dx%d", e.Frame.Width, e.Frame.Height)
+	}
+	// with a 90 degree FOV the projection plane is half the width of the frame away
+	if e.distanceToProjPlane < 319.9 || e.distanceToProjPlane > 320.1 {
+		t.Errorf("Expected the projection plane 320 pixels away. Received: %f", e.distanceToProjPlane)
+	}
+	if e.Player.turnSpeed != PI {
+		t.Errorf("The turn speed should be in degrees. Received: %f", e.Player.turnSpeed)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package raycaster
 
 import "math"
 
@@ -10,20 +10,33 @@ const (
 	PI    = math.Pi
 	TwoPI = 2.0 * PI
 
-	TileSize   = 64
-	MapNumRows = 13
-	MapNumCols = 20
+	TileSize = 64
 
 	MinimapScaleFactor = 0.2
-
-	WindowWidth  = MapNumCols * TileSize
-	WindowHeight = MapNumRows * TileSize
-
-	FOV     = 60 * (math.Pi / 180)
-	NumRays = WindowWidth
-
-	TextureWidth  = 64
-	TextureHeight = 64
+	MinimapMaxSize     = 0.3 // how much of the width and height of the window the minimap can cover
+
+	// the default size of the window. The pitch of the player is always in pixels of this size
+	// so looking up and down is the same whatever size the window is.
+	WindowWidth  = 1280
+	WindowHeight = 832
+
+	UseDistance = TileSize // how far in front of the player we look for things to use e.g. doors
+
+	// walls closer than this are projected as if they were this far. Standing right against
+	// a wall would divide by zero otherwise.
+	MinWallDistance = 0.01
+
+	EyeHeight        = TileSize / 2 // how high the camera is from the floor when standing
+	CrouchEyeHeight  = TileSize / 4
+	JumpSpeed        = 200              // how fast the player goes up when jumping
+	Gravity          = 800              // how fast the player falls back down
+	MaxPitch         = WindowHeight / 2 // how far the horizon can move up or down (pixels)
+	MouseSensitivity = 1.0              // pixels the horizon moves for every pixel the mouse moves
+
+	// the defaults for the config. See Config
+	FOV       = 60 * (math.Pi / 180)
+	WalkSpeed = 100 // pixels per second
+	TurnSpeed = 70  // degrees per second
 )
 
 // Some base colors
@@ -1,4 +1,4 @@
-package main
+package raycaster
 
 import (
 	"encoding/binary"
@@ -0,0 +1,138 @@
+package raycaster
+
+import "image/color"
+
+// MaxDecals is how many decals can be added while the game is running. Once there are
+// that many the oldest one is replaced so e.g. the first splats disappear.
+// Decals in the level file don't count.
+const MaxDecals = 64
+
+// Decal is an image on a face of a wall e.g. a sign, a splat or a bullet hole.
+// It's drawn on top of the wall texture using its alpha.
+type Decal struct {
+	TileFace
+	Texture string `json:"texture"` // name of the image in the images directory
+
+	// X and Y are where the top left corner of the decal is on the face in tiles. X goes from the left
+	// of the face when looking at it and Y down from the top of the wall. Width and Height are
+	// its size in tiles and default to 1 which covers the whole face of a normal wall.
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+
+	at faceKey // the face it is on. Set when it is placed.
+}
+
+// addLevelDecals puts the decals from the level on their faces. Decals that aren't on a wall face are skipped.
+func (gm *GameMap) addLevelDecals() {
+	gm.decals = make(map[faceKey][]*Decal)
+	for n, d := range gm.Level.Decals {
+		if err := gm.placeDecal(d); err != nil {
+			gm.warn("decal %d: %s. Skipping it.", n, err)
+		}
+	}
+}
+
+// placeDecal puts the decal on its face
+func (gm *GameMap) placeDecal(d *Decal) error {
+	key, err := d.key(gm.Level)
+	if err != nil {
+		return err
+	}
+	d.at = key
+	if d.Width <= 0 {
+		d.Width = 1
+	}
+	if d.Height <= 0 {
+		d.Height = 1
+	}
+	gm.decals[key] = append(gm.decals[key], d)
+	return nil
+}
+
+// AddDecal puts a decal on a wall while the game is running. If there are already MaxDecals
+// the oldest one is removed. Returns an error if it isn't on the face of a wall.
+func (gm *GameMap) AddDecal(d Decal) (*Decal, error) {
+	decal := &d
+	if err := gm.placeDecal(decal); err != nil {
+		return nil, err
+	}
+	if len(gm.addedDecals) >= MaxDecals {
+		gm.RemoveDecal(gm.addedDecals[0])
+	}
+	gm.addedDecals = append(gm.addedDecals, decal)
+	return decal, nil
+}
+
+// RemoveDecal removes a decal from its wall
+func (gm *GameMap) RemoveDecal(decal *Decal) {
+	decals := gm.decals[decal.at]
+	for i, d := range decals {
+		if d == decal {
+			gm.decals[decal.at] = append(decals[:i], decals[i+1:]...)
+			break
+		}
+	}
+	for i, d := range gm.addedDecals {
+		if d == decal {
+			gm.addedDecals = append(gm.addedDecals[:i], gm.addedDecals[i+1:]...)
+			break
+		}
+	}
+}
+
+// DecalsAt returns the decals on the face of the wall the ray hit
+func (gm *GameMap) DecalsAt(hit *wallHit) []*Decal {
+	face, i, j := wallFace(hit)
+	if face == faceNone {
+		return nil
+	}
+	return gm.decals[faceKey{i, j, face}]
+}
+
+// faceU returns how far across the face (0-1) the hit is from the left when looking at it.
+// The offset goes along the x or y axis so it's backwards on the faces looking down and left.
+func faceU(face int, offset float64) float64 {
+	u := offset / TileSize
+	if face == faceNorth || face == faceEast {
+		return 1 - u
+	}
+	return u
+}
+
+// applyDecals draws the decals that cover the point u (across the face) and down (tiles from
+// the top of the wall) on top of the texel. projectedWidth and projectedHeight are how big a tile is on the screen.
+func (e *Engine) applyDecals(texel color.NRGBA, decals []*Decal, u, down, projectedWidth, projectedHeight float64) color.NRGBA {
+	for _, d := range decals {
+		if u < d.X || u >= d.X+d.Width || down < d.Y || down >= d.Y+d.Height {
+			continue
+		}
+		texture := e.GameMap.Level.texture(d.Texture)
+		if e.sampling == SamplingMipmap {
+			texture = e.mipLevel(texture, projectedWidth*d.Width, projectedHeight*d.Height)
+		}
+		texel = over(texel, sampleTexture(texture, (u-d.X)/d.Width, (down-d.Y)/d.Height, e.sampling, false))
+	}
+	return texel
+}
+
+// over returns the color src on top of dst using their alpha
+func over(dst, src color.NRGBA) color.NRGBA {
+	if src.A == 255 || dst.A == 0 {
+		return src
+	}
+	if src.A == 0 {
+		return dst
+	}
+
+	sa := int(src.A)
+	da := int(dst.A) * (255 - sa) / 255 // how much of dst shows through src
+	a := sa + da
+	return color.NRGBA{
+		R: uint8((int(src.R)*sa + int(dst.R)*da) / a),
+		G: uint8((int(src.G)*sa + int(dst.G)*da) / a),
+		B: uint8((int(src.B)*sa + int(dst.B)*da) / a),
+		A: uint8(a),
+	}
+}
@@ -0,0 +1,74 @@
+package raycaster
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestAddDecalRecyclesTheOldest(t *testing.T) {
+	l := &Level{
+		Data: LevelData{
+			{1, 1, 1},
+			{1, 0, 1},
+			{1, 1, 1},
+		},
+		Decals: []*Decal{{TileFace: TileFace{Row: 0, Col: 1, Face: "south"}, Texture: "sign"}},
+	}
+	gm := NewGameMap(l)
+
+	face := TileFace{Row: 1, Col: 2, Face: "west"}
+	first, err := gm.AddDecal(Decal{TileFace: face, Texture: "splat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Width != 1 || first.Height != 1 {
+		t.Errorf("Expected the decal to cover the whole face by default. Received: %f x %f", first.Width, first.Height)
+	}
+	for i := 1; i < MaxDecals+1; i++ {
+		if _, err := gm.AddDecal(Decal{TileFace: face, Texture: "splat"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hit := &wallHit{x: 2 * TileSize, y: 1.5 * TileSize, face: faceWest}
+	decals := gm.DecalsAt(hit)
+	if len(decals) != MaxDecals {
+		t.Fatalf("Expected %d decals. Received: %d", MaxDecals, len(decals))
+	}
+	for _, d := range decals {
+		if d == first {
+			t.Error("Expected the oldest decal to be replaced")
+		}
+	}
+	// the one from the level doesn't count
+	if len(gm.DecalsAt(&wallHit{x: 1.5 * TileSize, y: TileSize, face: faceSouth})) != 1 {
+		t.Error("Expected the decal from the level to stay")
+	}
+
+	if _, err := gm.AddDecal(Decal{TileFace: TileFace{Row: 1, Col: 1, Face: "west"}}); err == nil {
+		t.Error("Expected an error for a decal on an empty tile")
+	}
+}
+
+func TestFaceU(t *testing.T) {
+	// looking at the north face we are looking down the map so its left is the right of the tile
+	if u := faceU(faceNorth, TileSize/4); u != 0.75 {
+		t.Errorf("Expected 0.75. Received: %f", u)
+	}
+	if u := faceU(faceSouth, TileSize/4); u != 0.25 {
+		t.Errorf("Expected 0.25. Received: %f", u)
+	}
+}
+
+func TestOver(t *testing.T) {
+	wall := color.NRGBA{R: 0, G: 0, B: 200, A: 255}
+	if c := over(wall, color.NRGBA{R: 200, A: 0}); c != wall {
+		t.Errorf("Transparent decals should leave the wall. Received: %v", c)
+	}
+	if c := over(wall, color.NRGBA{R: 200, A: 255}); c != (color.NRGBA{R: 200, A: 255}) {
+		t.Errorf("Opaque decals should cover the wall. Received: %v", c)
+	}
+	if c := over(wall, color.NRGBA{R: 200, A: 128}); c.R != 100 || c.B != 99 || c.A != 255 {
+		t.Errorf("Expected half of each. Received: %v", c)
+	}
+}
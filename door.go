@@ -0,0 +1,103 @@
+package raycaster
+
+import "math"
+
+// Door settings
+const (
+	DoorSpeed      = 1.0 // how much of the door opens/closes every second
+	DoorCloseDelay = 3.0 // seconds the door stays open before it starts closing
+	DoorPassable   = 0.7 // how open the door has to be for the player to walk through
+)
+
+type doorState int
+
+const (
+	doorClosed doorState = iota
+	doorOpening
+	doorOpen
+	doorClosing
+)
+
+// Door is a thin wall in the middle of the tile that slides open sideways.
+type Door struct {
+	i, j    int // row and column on the map
+	content int // the tile ID
+
+	// vertical doors run north to south (the player walks through them east-west).
+	// Horizontal doors run east to west.
+	vertical bool
+
+	open  float64 // 0 is fully closed and 1 fully open
+	state doorState
+	timer float64 // time left until the door starts closing
+}
+
+// Open starts opening the door unless it's already open
+func (d *Door) Open() {
+	if d.state == doorClosed || d.state == doorClosing {
+		d.state = doorOpening
+	}
+}
+
+// IsPassable returns true if the door is open enough for the player to walk through
+func (d *Door) IsPassable() bool {
+	return d.open >= DoorPassable
+}
+
+// Update moves the door and closes it again once the delay is over.
+// The door won't close while the player is standing in the doorway.
+func (d *Door) Update(deltaTime float64, player *Player) {
+	playerInside := player != nil &&
+		int(math.Floor(player.y/TileSize)) == d.i &&
+		int(math.Floor(player.x/TileSize)) == d.j
+
+	switch d.state {
+	case doorOpening:
+		d.open += DoorSpeed * deltaTime
+		if d.open >= 1 {
+			d.open = 1
+			d.state = doorOpen
+			d.timer = DoorCloseDelay
+		}
+	case doorOpen:
+		d.timer -= deltaTime
+		if d.timer <= 0 && !playerInside {
+			d.state = doorClosing
+		}
+	case doorClosing:
+		if playerInside {
+			d.state = doorOpening
+			return
+		}
+		d.open -= DoorSpeed * deltaTime
+		if d.open <= 0 {
+			d.open = 0
+			d.state = doorClosed
+		}
+	}
+}
+
+// hit checks if a ray that reaches the middle of the door tile at pos actually hits the door.
+// pos is the x coordinate for horizontal doors and the y coordinate for vertical ones.
+// Returns the offset along the door panel used for the texture.
+//
+//	Horizontal door sliding open to the right:
+//
+//	|   open   |=====door=====|
+//	0      open*TileSize   TileSize
+func (d *Door) hit(pos float64) (offset float64, ok bool) {
+	start := float64(d.j) * TileSize
+	if d.vertical {
+		start = float64(d.i) * TileSize
+	}
+
+	offset = pos - start
+	if offset < 0 || offset >= TileSize { // the ray left the tile before reaching the middle
+		return 0, false
+	}
+	if offset < d.open*TileSize { // went through the open part
+		return 0, false
+	}
+	// the texture slides along with the door
+	return offset - d.open*TileSize, true
+}
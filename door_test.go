@@ -0,0 +1,52 @@
+package raycaster
+
+import "testing"
+
+func TestDoorHit(t *testing.T) {
+	d := &Door{i: 2, j: 3}
+
+	if _, ok := d.hit(3*TileSize + 10); !ok {
+		t.Error("Closed door should be hit anywhere inside the tile")
+	}
+	if _, ok := d.hit(4*TileSize + 10); ok {
+		t.Error("Ray leaving the tile before the middle should not hit the door")
+	}
+
+	d.open = 0.5
+	if _, ok := d.hit(3*TileSize + 10); ok {
+		t.Error("Ray going through the open part should not hit the door")
+	}
+	if offset, ok := d.hit(3*TileSize + 40); !ok || offset != 40-TileSize/2 {
+		t.Errorf("Half open door should be hit with the texture shifted. Received: %f", offset)
+	}
+}
+
+func TestDoorOpenAndClose(t *testing.T) {
+	p := &Player{x: 0, y: 0}
+	d := &Door{i: 2, j: 3}
+
+	d.Open()
+	d.Update(0.5, p)
+	if d.IsPassable() {
+		t.Error("Door should not be passable while it's only half open")
+	}
+	d.Update(0.5, p)
+	if !d.IsPassable() || d.state != doorOpen {
+		t.Error("Door should be open after a second")
+	}
+
+	// the player standing in the doorway keeps it open
+	p.x, p.y = 3*TileSize+32, 2*TileSize+32
+	d.Update(DoorCloseDelay+1, p)
+	d.Update(1, p)
+	if d.state != doorOpen {
+		t.Error("Door should not close while the player is in the doorway")
+	}
+
+	p.x, p.y = 0, 0
+	d.Update(0.1, p)
+	d.Update(1, p)
+	if d.state != doorClosed || d.open != 0 {
+		t.Error("Door should close once the player leaves")
+	}
+}
@@ -0,0 +1,198 @@
+package raycaster
+
+import (
+	"image"
+	"math"
+
+	"github.com/kyriacos/colorbuffer"
+)
+
+// Engine is a single raycaster. It owns everything needed to run the game and draw it: the textures,
+// the map, the player, the rays and the frame they are drawn into. Nothing is shared between engines
+// so there can be as many of them as needed e.g. one for every test.
+type Engine struct {
+	Player  *Player
+	GameMap *GameMap
+	Rays    Rays
+
+	// Frame is the color buffer every frame is drawn into at the render resolution
+	Frame *colorbuffer.ColorBuffer
+
+	Time float64 // seconds since the game started. Animations use it to pick their frame.
+
+	running bool // the game loop keeps going until the player quits. See Run
+
+	fov                       float64 // in radians
+	windowWidth, windowHeight int     // the frame is stretched to the window
+	minimapScale              float64
+	sampling                  string
+
+	textures   map[string]*image.NRGBA         // every loaded texture by name (the file name without the extension)
+	mipmaps    map[*image.NRGBA][]*image.NRGBA // the mip levels of every loaded texture. See generateMipmaps
+	animations map[string]*Animation           // their current frame is in textures under the same name
+
+	// The distance from the player to the projection plane in columns and in rows. Same for every column.
+	// They are the same unless the pixels are stretched when the frame is shown (see setResolution).
+	// Anything horizontal uses distanceToProjPlane and anything vertical rowsToProjPlane.
+	distanceToProjPlane, rowsToProjPlane float64
+	rowDistanceScale                     []float64
+	skyPanoramaWidth                     float64
+
+	// The horizon row and the height of the camera above the floor for the current frame.
+	// The player can look up and down which moves the horizon (y-shearing) and can jump
+	// or crouch which moves the camera. Both are set at the start of project3d.
+	horizon   int
+	eyeHeight float64
+
+	// fogFloorWeights and fogCeilingWeights hold the fog weight for each row distance.
+	// They are recalculated every frame since the camera and the fog settings can change.
+	fogFloorWeights, fogCeilingWeights []int
+
+	// zBuffer holds the perpendicular distance to the closest wall for every column so
+	// sprites can be clipped behind walls. It's filled in by project3d.
+	zBuffer []float64
+
+	// reused every frame so drawing doesn't allocate anything
+	visibleHits []*wallHit      // the walls of the column that are not hidden behind closer walls
+	wallsOnTop  [][]*wallHit    // for every column the walls drawn after the sprites from the closest
+	seenThrough []Sprite        // copies of the sprites seen in mirrors and portals
+	seenOrder   []*Sprite       // the copies in seenThrough sorted from the furthest
+	views       []viewTransform // the different views the rays went through
+}
+
+// New loads the textures and the level and puts the player in the middle of the map
+// or on the open tile nearest to it
+func New(cfg Config) (*Engine, error) {
+	cfg = cfg.withDefaults()
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	e := &Engine{
+		fov:          *cfg.FOV * PI / 180,
+		windowWidth:  *cfg.WindowWidth,
+		windowHeight: *cfg.WindowHeight,
+		minimapScale: *cfg.MinimapScale,
+		sampling:     cfg.Sampling,
+		visibleHits:  make([]*wallHit, 0, 8),
+		seenThrough:  make([]Sprite, 0, 8),
+		seenOrder:    make([]*Sprite, 0, 8),
+		views:        make([]viewTransform, 0, 8),
+	}
+	if err := e.loadTextures(cfg.ImageDir); err != nil {
+		return nil, err
+	}
+
+	level, err := LoadLevel(cfg.Level, e.textures)
+	if err != nil {
+		return nil, err
+	}
+	e.GameMap = NewGameMap(level)
+
+	x, y, angle := e.GameMap.start()
+	e.Player = &Player{
+		x:             x,
+		y:             y,
+		width:         1,
+		height:        1,
+		turnDirection: 0,
+		walkDirection: 0,
+		rotationAngle: angle,
+		walkSpeed:     *cfg.WalkSpeed,
+		turnSpeed:     *cfg.TurnSpeed * PI / 180,
+		pitchSpeed:    WindowHeight / 2,
+	}
+
+	e.setResolution(cfg.Width, cfg.Height)
+	return e, nil
+}
+
+// Update moves everything deltaTime seconds forward and casts the rays for the next frame
+func (e *Engine) Update(deltaTime float64) {
+	e.Time += deltaTime
+	e.updateAnimations()
+
+	e.Player.Update(e.GameMap, deltaTime)
+	e.GameMap.Update(deltaTime, e.Player)
+
+	e.castAllRays()
+}
+
+// Render draws what the player sees into the Frame
+func (e *Engine) Render() {
+	e.project3d()
+}
+
+// RenderMinimap draws the map, the player and the rays on top of whatever the canvas already has.
+// The minimap is drawn in window pixels so it's the same size at any render resolution.
+// Big maps only show the part around the player. See Minimap.
+func (e *Engine) RenderMinimap(c Canvas) {
+	m := newMinimap(c, e.GameMap, e.minimapScale, e.Player.x, e.Player.y, e.windowWidth, e.windowHeight)
+	e.GameMap.Render(m)
+	e.Player.Render(m)
+
+	for _, ray := range e.Rays {
+		ray.Render(m, e.Player.x, e.Player.y)
+	}
+}
+
+// Warnings returns the problems with the level that were worked around when it was loaded
+// e.g. missing textures. Nothing is printed so it's up to the caller to show them.
+func (e *Engine) Warnings() []string {
+	warnings := append([]string(nil), e.GameMap.Level.Warnings...)
+	return append(warnings, e.GameMap.Warnings...)
+}
+
+// Use activates whatever is right in front of the player e.g. opens a door.
+// Returns true if the player found a secret. GameMap.SecretsFound has how many they found so far.
+func (e *Engine) Use() bool {
+	return e.Player.use(e.GameMap)
+}
+
+// ToggleTorch turns the light the player is carrying on or off
+func (e *Engine) ToggleTorch() error {
+	return e.Player.toggleTorch(e.GameMap)
+}
+
+// Spray puts a splat on the wall in the middle of the screen
+func (e *Engine) Spray() error {
+	return e.Player.spray(e.GameMap, e.Rays[len(e.Rays)/2])
+}
+
+func (e *Engine) castAllRays() {
+	// initial ray angle
+	angle := e.Player.rotationAngle - (e.fov / 2)
+
+	for _, ray := range e.Rays {
+		ray.Cast(e.GameMap, e.Player.x, e.Player.y, angle)
+		angle += e.fov / float64(len(e.Rays))
+	}
+}
+
+// setResolution changes the render resolution and recalculates everything that depends on it.
+// There's a ray for every column.
+//
+// When the frame is a different shape than the window the pixels end up stretched so they
+// aren't square anymore. The projection plane is further away (in rows) for pixels that are
+// taller than they are wide so walls and sprites have the right shape on the window.
+func (e *Engine) setResolution(width, height int) {
+	e.Frame = colorbuffer.NewColorBuffer(width, height)
+	e.Rays = NewRays(width)
+
+	e.distanceToProjPlane = float64(width/2) / math.Tan(e.fov/2)
+	pixelAspect := (float64(e.windowHeight) / float64(height)) / (float64(e.windowWidth) / float64(width))
+	e.rowsToProjPlane = e.distanceToProjPlane / pixelAspect
+
+	e.horizon = height / 2
+	e.rowDistanceScale = e.newRowDistanceScale()
+	e.fogFloorWeights = make([]int, len(e.rowDistanceScale))
+	e.fogCeilingWeights = make([]int, len(e.rowDistanceScale))
+	e.zBuffer = make([]float64, width)
+	e.wallsOnTop = make([][]*wallHit, width)
+	e.skyPanoramaWidth = float64(width) * TwoPI / e.fov
+}
+
+// pitchRows converts the pitch of the player from pixels of the default window (see WindowHeight) to rows of the frame
+func (e *Engine) pitchRows(pitch float64) int {
+	return int(pitch * float64(e.Frame.Height) / WindowHeight)
+}
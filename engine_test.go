@@ -0,0 +1,55 @@
+package raycaster
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestSetResolution(t *testing.T) {
+	e := &Engine{fov: FOV, windowWidth: WindowWidth, windowHeight: WindowHeight}
+
+	// half the width of the window but the full height so every pixel is twice as wide as it is tall
+	e.setResolution(WindowWidth/2, WindowHeight)
+	if len(e.Rays) != WindowWidth/2 || len(e.zBuffer) != len(e.Rays) || e.Frame.Width != WindowWidth/2 {
+		t.Errorf("There should be a ray for every column. Received: %d rays and %d distances", len(e.Rays), len(e.zBuffer))
+	}
+	if math.Abs(e.rowsToProjPlane-2*e.distanceToProjPlane) > 1e-9 {
+		t.Errorf("Rows should be projected twice as far as columns. Received: %f and %f", e.rowsToProjPlane, e.distanceToProjPlane)
+	}
+	if len(e.rowDistanceScale) != WindowHeight+WindowHeight/2+1 {
+		t.Errorf("Row distances should cover the screen and the pitch. Received: %d", len(e.rowDistanceScale))
+	}
+}
+
+func TestEnginesDontShareAnything(t *testing.T) {
+	a, err := New(Config{Width: 320, Height: 200})
+	if err != nil {
+		t.Fatalf("Failed to create the engine: %s", err)
+	}
+	b, err := New(Config{Width: 320, Height: 200})
+	if err != nil {
+		t.Fatalf("Failed to create the engine: %s", err)
+	}
+
+	a.Update(0)
+	a.Render()
+	b.Update(0)
+	b.Render()
+	if !bytes.Equal(a.Frame.Pixels, b.Frame.Pixels) {
+		t.Fatal("Engines in the same place should draw the same frame")
+	}
+
+	// turning one of them around only changes its own frame
+	x, y, angle := b.Player.Position()
+	b.Player.Place(x, y, angle+PI)
+	b.Update(0)
+	b.Render()
+	a.Render()
+	if bytes.Equal(a.Frame.Pixels, b.Frame.Pixels) {
+		t.Error("Engines looking in different directions should draw different frames")
+	}
+	if a.Player == b.Player || a.GameMap == b.GameMap || a.Rays[0] == b.Rays[0] {
+		t.Error("Engines should have their own player, map and rays")
+	}
+}
@@ -0,0 +1,84 @@
+package raycaster
+
+import (
+	"math"
+
+	"github.com/kyriacos/colorbuffer"
+)
+
+// Fog modes
+const (
+	FogNone        = "none"
+	FogLinear      = "linear"
+	FogExponential = "exponential"
+)
+
+// Fog holds the depth shading settings. The further away something is the more it gets
+// blended with the fog color. Use a black fog color to simply darken things in the distance.
+type Fog struct {
+	Mode  string   `json:"mode"`
+	Color HexColor `json:"color"`
+
+	// Start is the distance where the fog begins. Anything closer is drawn at full brightness.
+	// End is where linear fog completely covers everything.
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+
+	// Density is only used by exponential fog. If it's not set we pick a density
+	// so the fog is at about 95% at the End distance.
+	Density float64 `json:"density"`
+}
+
+// DefaultFog is used by every level unless the level overrides it
+var DefaultFog = Fog{
+	Mode:  FogLinear,
+	Color: HexColor{R: 0, G: 0, B: 0, A: 255},
+	Start: TileSize,
+	End:   16 * TileSize,
+}
+
+// Amount returns how much fog there is at the (perpendicular) distance.
+// 0 is no fog at all and 1 means only the fog color is visible.
+func (f *Fog) Amount(distance float64) float64 {
+	if distance <= f.Start {
+		return 0
+	}
+
+	switch f.Mode {
+	case FogLinear:
+		if distance >= f.End || f.End <= f.Start {
+			return 1
+		}
+		return (distance - f.Start) / (f.End - f.Start)
+	case FogExponential:
+		density := f.Density
+		if density <= 0 && f.End > f.Start {
+			density = 3 / (f.End - f.Start) // e^-3 ~= 0.05
+		}
+		return 1 - math.Exp(-density*(distance-f.Start))
+	}
+	return 0
+}
+
+// on returns true if the fog mode adds any fog at all
+func (f *Fog) on() bool {
+	return f.Mode == FogLinear || f.Mode == FogExponential
+}
+
+// weight converts the fog amount at the distance to an integer weight (0-256) so we can blend
+// pixels without using floats
+func (f *Fog) weight(distance float64) int {
+	return int(f.Amount(distance) * 256)
+}
+
+// fogPixel blends the pixel already in the color buffer with the fog color using the weight (0-256)
+func fogPixel(cb *colorbuffer.ColorBuffer, column, row int, c HexColor, weight int) {
+	if weight <= 0 {
+		return
+	}
+	o := cb.PixelOffset(column, row)
+	px := cb.Pixels[o : o+3 : o+3]
+	px[0] = uint8((int(px[0])*(256-weight) + int(c.R)*weight) >> 8)
+	px[1] = uint8((int(px[1])*(256-weight) + int(c.G)*weight) >> 8)
+	px[2] = uint8((int(px[2])*(256-weight) + int(c.B)*weight) >> 8)
+}
@@ -0,0 +1,53 @@
+package raycaster
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestFogAmount(t *testing.T) {
+	linear := Fog{Mode: FogLinear, Start: 100, End: 300}
+	cases := []struct {
+		distance, expected float64
+	}{
+		{50, 0},
+		{100, 0},
+		{200, 0.5},
+		{300, 1},
+		{1000, 1},
+	}
+	for _, c := range cases {
+		if a := linear.Amount(c.distance); a != c.expected {
+			t.Errorf("Linear fog at %f. Received: %f. Expected: %f", c.distance, a, c.expected)
+		}
+	}
+
+	exp := Fog{Mode: FogExponential, Start: 100, End: 300}
+	if a := exp.Amount(300); math.Abs(a-0.95) > 0.01 {
+		t.Errorf("Exponential fog should be at about 95%% at the end distance. Received: %f", a)
+	}
+
+	none := Fog{Mode: FogNone, Start: 0, End: 1}
+	if a := none.Amount(1000); a != 0 {
+		t.Errorf("Fog mode none should never add fog. Received: %f", a)
+	}
+}
+
+func TestLevelFogOverride(t *testing.T) {
+	l := Level{Fog: DefaultFog}
+	data := `{"fog": {"mode": "exponential", "color": "#102030"}}`
+	if err := json.Unmarshal([]byte(data), &l); err != nil {
+		t.Fatalf("Failed to decode level: %s", err)
+	}
+
+	if l.Fog.Mode != FogExponential {
+		t.Errorf("Fog mode was not overridden. Received: %s", l.Fog.Mode)
+	}
+	if l.Fog.Color != (HexColor{R: 0x10, G: 0x20, B: 0x30, A: 0xFF}) {
+		t.Errorf("Fog color was not parsed correctly. Received: %v", l.Fog.Color)
+	}
+	if l.Fog.Start != DefaultFog.Start || l.Fog.End != DefaultFog.End {
+		t.Error("Fog settings that are not in the level should keep the default values")
+	}
+}
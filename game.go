@@ -1,10 +1,124 @@
-package main
+package raycaster
 
-type Game struct {
-	Running        bool   //= false
-	TicksLastFrame uint32 // = 0
+import (
+	"fmt"
+	"log"
+)
 
-	Player  *Player
-	GameMap *GameMap
-	Rays    *Rays
+// Run opens the backend and runs the game loop until the player quits. With showFPS the FPS
+// is printed every frame and the average FPS once the game is over.
+func (e *Engine) Run(b Backend, showFPS bool) error {
+	if err := b.Open("RayCaster", e.windowWidth, e.windowHeight, e.Frame.Width, e.Frame.Height); err != nil {
+		return err
+	}
+	defer b.Close()
+
+	var (
+		counter         = 0
+		elapsed, sumFPS float64
+	)
+
+	e.running = true
+	for e.running {
+		start := b.Now()
+
+		e.processInput(b)
+		e.Update(elapsed)
+		e.render(b)
+
+		end := b.Now()
+		elapsed = end - start
+
+		b.Delay(FrameTimeLength/1000.0 - elapsed) // pause until we reach the target frames
+
+		if showFPS && elapsed > 0 {
+			counter++
+			currentFPS := 1.0 / elapsed
+			sumFPS += currentFPS
+
+			fmt.Printf("FPS: %f\n", currentFPS)
+		}
+	}
+
+	if showFPS && counter > 0 {
+		fmt.Printf("Average FPS: %f\n", sumFPS/float64(counter))
+	}
+	return nil
+}
+
+func (e *Engine) render(b Backend) {
+	e.Render()
+	b.DrawFrame(e.Frame)
+
+	// render the minimap on top of the current frame
+	e.RenderMinimap(b)
+
+	b.Present()
+}
+
+func (e *Engine) processInput(b Backend) {
+	for event, ok := b.PollEvent(); ok; event, ok = b.PollEvent() {
+		switch event.Type {
+		case QuitEvent:
+			e.running = false
+		case KeyDownEvent:
+			e.keyDown(event.Key)
+		case KeyUpEvent:
+			e.keyUp(event.Key)
+		case MouseMotionEvent:
+			// moving the mouse up looks up
+			e.Player.Look(-float64(event.YRel) * MouseSensitivity)
+		}
+	}
+}
+
+func (e *Engine) keyDown(key Key) {
+	switch key {
+	case KeyEscape:
+		e.running = false
+	case KeyUp:
+		e.Player.Walk(1)
+	case KeyDown:
+		e.Player.Walk(-1)
+	case KeyRight:
+		e.Player.Turn(1)
+	case KeyLeft:
+		e.Player.Turn(-1)
+	case KeySpace:
+		if e.Use() {
+			found, total := e.GameMap.SecretsFound()
+			log.Printf("Found a secret! %d/%d", found, total)
+		}
+	case KeyPageUp:
+		e.Player.Tilt(1)
+	case KeyPageDown:
+		e.Player.Tilt(-1)
+	case KeyEnd: // look straight ahead again
+		e.Player.LookAhead()
+	case KeyJ:
+		e.Player.Jump()
+	case KeyC:
+		e.Player.Crouch(true)
+	case KeyT:
+		if err := e.ToggleTorch(); err != nil {
+			log.Print(err)
+		}
+	case KeyG:
+		if err := e.Spray(); err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+func (e *Engine) keyUp(key Key) {
+	switch key {
+	case KeyUp, KeyDown:
+		e.Player.Walk(0)
+	case KeyRight, KeyLeft:
+		e.Player.Turn(0)
+	case KeyPageUp, KeyPageDown:
+		e.Player.Tilt(0)
+	case KeyC:
+		e.Player.Crouch(false)
+	}
 }
@@ -1,59 +1,323 @@
-package main
+package raycaster
 
 import (
+	"fmt"
+	"image/color"
 	"math"
-
-	"github.com/veandco/go-sdl2/sdl"
 )
 
 // GameMap - comment
 type GameMap struct {
 	Level *Level
+
+	doors     map[tileIndex]*Door
+	pushwalls []*Pushwall        // pushwalls that are moving right now
+	secrets   map[tileIndex]bool // pushwalls by their starting tile and if they have been found
+
+	maxWallHeight float64 // height of the tallest wall in the level
+
+	lightmap *Lightmap // nil if the level doesn't have any lights
+
+	lights       []*Light                     // dynamic lights added while the game is running
+	dynamicFloor [MaxDynamicLights]lightPatch // light from each dynamic light on the floor around it
+	dynamicLit   int                          // how many of dynamicFloor are lit
+
+	portals map[faceKey]*portalExit // where each portal face comes out
+
+	decals      map[faceKey][]*Decal // on every face that has any
+	addedDecals []*Decal             // added while the game is running from the oldest
+
+	// Warnings are the problems NewGameMap found in the level and worked around e.g. decals that
+	// aren't on a wall. The ones LoadLevel found are in the level.
+	Warnings []string
+}
+
+// tileIndex is the row and column of a tile on the map
+type tileIndex struct {
+	i, j int
 }
 
 func NewGameMap(l *Level) *GameMap {
-	return &GameMap{Level: l}
+	gm := &GameMap{
+		Level:   l,
+		doors:   make(map[tileIndex]*Door),
+		secrets: make(map[tileIndex]bool),
+	}
+
+	for i, row := range l.Data {
+		for j, content := range row {
+			if content == 0 {
+				continue
+			}
+
+			gm.maxWallHeight = math.Max(gm.maxWallHeight, l.WallHeight(content))
+
+			switch l.Tile(content).Type {
+			case TileDoor:
+				// a door with walls to the left and right of it runs east to west. Otherwise north to south.
+				vertical := !(j > 0 && row[j-1] != 0 && j < len(row)-1 && row[j+1] != 0)
+				gm.doors[tileIndex{i, j}] = &Door{i: i, j: j, content: content, vertical: vertical}
+			case TilePushwall:
+				gm.secrets[tileIndex{i, j}] = false
+			}
+		}
+	}
+
+	gm.addPortals()
+	gm.addLevelDecals()
+	gm.lightmap = bakeLightmap(gm)
+
+	return gm
+}
+
+// warn adds a warning about the level
+func (gm *GameMap) warn(format string, a ...interface{}) {
+	gm.Warnings = append(gm.Warnings, fmt.Sprintf("Level %s: ", gm.Level.ID)+fmt.Sprintf(format, a...))
+}
+
+// start returns where the player starts and which way they are facing. That's the middle of the map
+// or the middle of the open tile nearest to it if it's a wall.
+func (gm *GameMap) start() (x, y, angle float64) {
+	// facing west like the player always used to
+	x, y = gm.Width()/2, gm.Height()/2
+	if !gm.HasWallAt(x, y) {
+		return x, y, PI
+	}
+
+	// the middle is a wall so use the middle of the open tile nearest to it
+	startX, startY, nearest := x, y, math.Inf(1)
+	for i := 0; i < gm.Level.Rows(); i++ {
+		for j := 0; j < gm.Level.Cols(); j++ {
+			tileX, tileY := (float64(j)+0.5)*TileSize, (float64(i)+0.5)*TileSize
+			if d := distanceBetweenPoints(x, y, tileX, tileY); d < nearest && !gm.HasWallAt(tileX, tileY) {
+				startX, startY, nearest = tileX, tileY, d
+			}
+		}
+	}
+	return startX, startY, PI
+}
+
+// Width and Height are the size of the map in pixels
+func (gm *GameMap) Width() float64 {
+	return float64(gm.Level.Cols() * TileSize)
+}
+func (gm *GameMap) Height() float64 {
+	return float64(gm.Level.Rows() * TileSize)
 }
 
 func (gm *GameMap) HasWallAt(x float64, y float64) bool {
-	if x < 0 || x > WindowWidth || y < 0 || y > WindowHeight {
+	if x < 0 || x >= gm.Width() || y < 0 || y >= gm.Height() {
 		return true
 	}
 
+	for _, pw := range gm.pushwalls {
+		if pw.contains(x, y) {
+			return true
+		}
+	}
+
 	mapGridIndexX := int(math.Floor(x / TileSize))
 	mapGridIndexY := int(math.Floor(y / TileSize))
 
-	return gm.Level.At(mapGridIndexY, mapGridIndexX) != 0
+	// doors only block when they are not open enough
+	if door, ok := gm.doors[tileIndex{mapGridIndexY, mapGridIndexX}]; ok {
+		return !door.IsPassable()
+	}
+
+	content := gm.Level.At(mapGridIndexY, mapGridIndexX)
+	if content == 0 || gm.Level.Tile(content).Passable {
+		return false
+	}
+
+	// only the segments in a segment tile are solid
+	if gm.HasSegments(content) {
+		for _, s := range gm.Level.Tile(content).Segments {
+			if s.distanceTo(mapGridIndexY, mapGridIndexX, x, y) < SegmentThickness {
+				return true
+			}
+		}
+		return false
+	}
+	return true
 }
 
-func (gm *GameMap) Render() {
-	// add a rectangle so the walls don't show up when moving around. make the map opaque
-	Renderer.SetDrawColor(0, 0, 0, 255)
-	Renderer.FillRect(&sdl.Rect{
-		X: 0,
-		Y: 0,
-		W: minimapScale(MapNumCols * TileSize),
-		H: minimapScale(MapNumRows * TileSize),
+// ContentAt returns the tile ID at x, y or 0 if it's outside of the map
+func (gm *GameMap) ContentAt(x float64, y float64) int {
+	i, j := int(math.Floor(y/TileSize)), int(math.Floor(x/TileSize))
+	if i < 0 || i >= len(gm.Level.Data) || j < 0 || j >= len(gm.Level.Data[i]) {
+		return 0
+	}
+	return gm.Level.At(i, j)
+}
+
+// BlocksView returns true if nothing can be seen behind the tile i.e. it's as tall as the tallest wall
+// in the level and we can't see through it. Mirrors always do since rays bounce off of them.
+func (gm *GameMap) BlocksView(content int) bool {
+	if gm.IsMirror(content) {
+		return true
+	}
+	return !gm.IsTransparent(content) && gm.Level.WallHeight(content) >= gm.maxWallHeight
+}
+
+// IsMirror returns true if the tile reflects
+func (gm *GameMap) IsMirror(content int) bool {
+	return content != 0 && gm.Level.Tile(content).Type == TileMirror
+}
+
+// HasSegments returns true if the walls in the tile are segments instead of the whole tile
+func (gm *GameMap) HasSegments(content int) bool {
+	return content != 0 && gm.Level.Tile(content).Type == TileSegment
+}
+
+// IsTransparent returns true if the tile can be seen through
+func (gm *GameMap) IsTransparent(content int) bool {
+	return gm.Level.Tile(content).Transparent
+}
+
+// DoorAt returns the door at x, y or nil if there isn't one
+func (gm *GameMap) DoorAt(x float64, y float64) *Door {
+	return gm.doors[tileIndex{int(math.Floor(y / TileSize)), int(math.Floor(x / TileSize))}]
+}
+
+// MovingPushwallAt returns the pushwall that is moving through the tile at x, y or nil if there isn't one
+func (gm *GameMap) MovingPushwallAt(x float64, y float64) *Pushwall {
+	i, j := int(math.Floor(y/TileSize)), int(math.Floor(x/TileSize))
+	for _, pw := range gm.pushwalls {
+		if pw.covers(i, j) {
+			return pw
+		}
+	}
+	return nil
+}
+
+// PushwallHit returns the closest point where the ray hits a moving pushwall
+func (gm *GameMap) PushwallHit(x, y, angle float64) (closest wallHit, found bool) {
+	for _, pw := range gm.pushwalls {
+		if hit, ok := pw.intersect(x, y, angle); ok && (!found || hit.distance < closest.distance) {
+			closest = hit
+			found = true
+		}
+	}
+	return closest, found
+}
+
+// Push starts moving the pushwall at x, y in the direction of the angle (rounded to the closest axis).
+// It moves up to PushwallDistance tiles or until something is in the way. Returns false if there
+// is no pushwall there or it can't move.
+func (gm *GameMap) Push(x, y, angle float64) bool {
+	idx := tileIndex{int(math.Floor(y / TileSize)), int(math.Floor(x / TileSize))}
+	if found, ok := gm.secrets[idx]; !ok || found {
+		return false
+	}
+
+	di, dj := 0, 0
+	if cos, sin := math.Cos(angle), math.Sin(angle); math.Abs(cos) > math.Abs(sin) {
+		dj = int(math.Copysign(1, cos))
+	} else {
+		di = int(math.Copysign(1, sin))
+	}
+
+	tiles := 0
+	for k := 1; k <= PushwallDistance; k++ {
+		i, j := idx.i+k*di, idx.j+k*dj
+		if i < 0 || i >= len(gm.Level.Data) || j < 0 || j >= len(gm.Level.Data[i]) ||
+			gm.Level.At(i, j) != 0 || gm.doors[tileIndex{i, j}] != nil ||
+			gm.MovingPushwallAt((float64(j)+0.5)*TileSize, (float64(i)+0.5)*TileSize) != nil {
+			break
+		}
+		tiles++
+	}
+	if tiles == 0 {
+		return false
+	}
+
+	gm.pushwalls = append(gm.pushwalls, &Pushwall{
+		i:         idx.i,
+		j:         idx.j,
+		content:   gm.Level.At(idx.i, idx.j),
+		di:        di,
+		dj:        dj,
+		tilesLeft: tiles - 1,
 	})
+	gm.Level.Data[idx.i][idx.j] = 0
+	gm.secrets[idx] = true
+	return true
+}
+
+// SecretsFound returns how many secrets the player has found and how many there are in the level
+func (gm *GameMap) SecretsFound() (found, total int) {
+	for _, f := range gm.secrets {
+		if f {
+			found++
+		}
+	}
+	return found, len(gm.secrets)
+}
+
+// Update updates everything on the map that moves e.g. doors. Doors don't close on the player.
+func (gm *GameMap) Update(deltaTime float64, player *Player) {
+	for _, door := range gm.doors {
+		door.Update(deltaTime, player)
+	}
 
-	for i := 0; i < MapNumRows; i++ {
-		for j := 0; j < MapNumCols; j++ {
+	// put pushwalls that stopped moving back on the grid
+	moving := gm.pushwalls[:0]
+	for _, pw := range gm.pushwalls {
+		if pw.Update(deltaTime) {
+			gm.Level.Data[pw.i][pw.j] = pw.content
+			continue
+		}
+		moving = append(moving, pw)
+	}
+	gm.pushwalls = moving
+}
+
+// Render draws the map on the minimap. Only the tiles inside the minimap's view are drawn.
+func (gm *GameMap) Render(m *Minimap) {
+	tileSize := int(math.Floor(m.scale * TileSize))
+	if tileSize < 1 { // too small to see anything
+		return
+	}
+
+	// add a rectangle so the walls don't show up when moving around. make the map opaque
+	m.FillRect(0, 0, m.width, m.height, color.NRGBA{0, 0, 0, 255})
+
+	// only the tiles that are on the minimap are drawn
+	firstRow, firstCol := int(m.y/TileSize), int(m.x/TileSize)
+	rows := minInt(gm.Level.Rows(), firstRow+m.height/tileSize+2)
+	cols := minInt(gm.Level.Cols(), firstCol+m.width/tileSize+2)
+	for i := firstRow; i < rows; i++ {
+		for j := firstCol; j < cols; j++ {
 			tileX := j * TileSize // column
 			tileY := i * TileSize // row
 
 			var tileColor uint8 = 0
-			if gm.Level.At(i, j) != 0 {
+			if content := gm.Level.At(i, j); content != 0 && !gm.HasSegments(content) {
 				tileColor = 255
 			}
 
-			Renderer.SetDrawColor(tileColor, tileColor, tileColor, 255)
-			rect := &sdl.Rect{
-				X: int32(MinimapScaleFactor * float64(tileX)),
-				Y: int32(MinimapScaleFactor * float64(tileY)),
-				W: int32(math.Floor(MinimapScaleFactor * TileSize)),
-				H: int32(math.Floor(MinimapScaleFactor * TileSize)),
+			x, y := m.point(float64(tileX), float64(tileY))
+			m.FillRect(x, y, tileSize, tileSize, color.NRGBA{tileColor, tileColor, tileColor, 255})
+
+			if content := gm.Level.At(i, j); gm.HasSegments(content) {
+				for _, s := range gm.Level.Tile(content).Segments {
+					x1, y1, x2, y2 := s.world(i, j)
+					m.drawLine(x1, y1, x2, y2, color.NRGBA{255, 255, 255, 255})
+				}
 			}
-			Renderer.FillRect(rect)
 		}
 	}
+
+	for _, pw := range gm.pushwalls {
+		minX, minY, _, _ := pw.bounds()
+		x, y := m.point(minX, minY)
+		m.FillRect(x, y, tileSize, tileSize, color.NRGBA{255, 255, 255, 255})
+	}
+
+	// portals are a line along their face
+	for k := range gm.portals {
+		x, y := k.center()
+		dx, dy := faceNormals[k.face][1]*TileSize/2, faceNormals[k.face][0]*TileSize/2
+		m.drawLine(x-dx, y-dy, x+dx, y+dy, color.NRGBA{0, 200, 255, 255})
+	}
 }
@@ -0,0 +1,139 @@
+package raycaster
+
+import (
+	"image/color"
+	"sort"
+
+	"github.com/kyriacos/colorbuffer"
+)
+
+// Headless is a backend without a window. Everything is drawn into Screen in memory and the input comes
+// from a script so the whole game loop can run without a display e.g. in tests or on CI.
+// The clock is fake and every frame takes exactly FrameTime seconds so runs are always the same.
+type Headless struct {
+	Screen *colorbuffer.ColorBuffer // the window. It's created by Open
+	Frames int                      // how many frames have been presented so far
+
+	FrameTime float64 // seconds every frame takes on the fake clock
+
+	// OnPresent is called every time a frame is presented with the number of the frame (starting from 0)
+	// and the screen. It's optional.
+	OnPresent func(frame int, screen *colorbuffer.ColorBuffer)
+
+	frames int             // the game quits after this many frames
+	script []ScriptedEvent // what's left of the script in the order it's sent
+	quit   bool
+	now    float64
+}
+
+// ScriptedEvent is an input event the headless backend sends at the start of frame Frame (starting from 0)
+type ScriptedEvent struct {
+	Frame int
+	Event
+}
+
+// NewHeadless returns a headless backend that plays the script and quits after the given number of frames.
+// Events are sent in the order of their frame and the ones for the same frame in the order they are given.
+func NewHeadless(frames int, script ...ScriptedEvent) *Headless {
+	s := make([]ScriptedEvent, len(script))
+	copy(s, script)
+	sort.SliceStable(s, func(i, j int) bool { return s[i].Frame < s[j].Frame })
+
+	return &Headless{
+		FrameTime: 1.0 / FPS,
+		frames:    frames,
+		script:    s,
+	}
+}
+
+func (h *Headless) Open(title string, width, height, frameWidth, frameHeight int) error {
+	h.Screen = colorbuffer.NewColorBuffer(width, height)
+	h.Screen.Clear(nrgbaToUint32(ColorBlack))
+	return nil
+}
+
+func (h *Headless) Close() {}
+
+func (h *Headless) PollEvent() (Event, bool) {
+	if len(h.script) > 0 && h.script[0].Frame <= h.Frames {
+		event := h.script[0].Event
+		h.script = h.script[1:]
+		return event, true
+	}
+
+	// the loop still finishes the frame it quits on so quit at the start of the last one
+	if !h.quit && h.Frames >= h.frames-1 {
+		h.quit = true
+		return Event{Type: QuitEvent}, true
+	}
+	return Event{}, false
+}
+
+// DrawFrame stretches the frame to the screen picking the closest pixel
+func (h *Headless) DrawFrame(frame *colorbuffer.ColorBuffer) {
+	for y := 0; y < h.Screen.Height; y++ {
+		src := frame.PixelOffset(0, y*frame.Height/h.Screen.Height)
+		dst := h.Screen.PixelOffset(0, y)
+		for x := 0; x < h.Screen.Width; x++ {
+			s := src + x*frame.Width/h.Screen.Width*4
+			copy(h.Screen.Pixels[dst+x*4:dst+x*4+4], frame.Pixels[s:s+4])
+		}
+	}
+}
+
+func (h *Headless) FillRect(x, y, w, height int, c color.NRGBA) {
+	for row := maxInt(y, 0); row < minInt(y+height, h.Screen.Height); row++ {
+		for column := maxInt(x, 0); column < minInt(x+w, h.Screen.Width); column++ {
+			blendPixel(h.Screen, column, row, c, HexColor{}, 0)
+		}
+	}
+}
+
+// DrawLine draws a line between the two points including both of them (Bresenham)
+func (h *Headless) DrawLine(x1, y1, x2, y2 int, c color.NRGBA) {
+	dx, dy := abs(x2-x1), -abs(y2-y1)
+	sx, sy := 1, 1
+	if x1 > x2 {
+		sx = -1
+	}
+	if y1 > y2 {
+		sy = -1
+	}
+
+	err := dx + dy
+	for {
+		if x1 >= 0 && x1 < h.Screen.Width && y1 >= 0 && y1 < h.Screen.Height {
+			blendPixel(h.Screen, x1, y1, c, HexColor{}, 0)
+		}
+		if x1 == x2 && y1 == y2 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x1 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y1 += sy
+		}
+	}
+}
+
+func (h *Headless) Present() {
+	if h.OnPresent != nil {
+		h.OnPresent(h.Frames, h.Screen)
+	}
+	h.Frames++
+	h.now += h.FrameTime
+}
+
+func (h *Headless) Now() float64 {
+	return h.now
+}
+
+func (h *Headless) Delay(seconds float64) {
+	if seconds > 0 {
+		h.now += seconds
+	}
+}
@@ -0,0 +1,132 @@
+package raycaster
+
+import (
+	"encoding/json"
+	"image/color"
+	"os"
+	"testing"
+
+	"github.com/kyriacos/colorbuffer"
+)
+
+func TestHeadlessRunsTheGameLoop(t *testing.T) {
+	e, err := New(Config{Width: 320, Height: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	startX, startY, _ := e.Player.Position()
+
+	// walk forward for half a second then stop
+	b := NewHeadless(30,
+		ScriptedEvent{Frame: 0, Event: Event{Type: KeyDownEvent, Key: KeyUp}},
+		ScriptedEvent{Frame: 15, Event: Event{Type: KeyUpEvent, Key: KeyUp}},
+	)
+	presented := 0
+	b.OnPresent = func(frame int, screen *colorbuffer.ColorBuffer) {
+		if frame != presented {
+			t.Errorf("Frame should be %d. Received: %d", presented, frame)
+		}
+		presented++
+	}
+
+	if err := e.Run(b, false); err != nil {
+		t.Fatal(err)
+	}
+	if b.Frames != 30 || presented != 30 {
+		t.Errorf("The game should quit after 30 frames. Received: %d", b.Frames)
+	}
+	if b.Screen.Width != WindowWidth || b.Screen.Height != WindowHeight {
+		t.Errorf("The screen should be the size of the window. Received: %dx%d", b.Screen.Width, b.Screen.Height)
+	}
+
+	// the player is looking left and walks at 100 pixels a second
+	x, y, _ := e.Player.Position()
+	if moved := startX - x; moved < 45 || moved > 55 || y != startY {
+		t.Errorf("The player should have walked ~50 pixels left. Received: %f, %f", x-startX, y-startY)
+	}
+
+	// the frame is stretched to the screen and the minimap is on top of it
+	if b.Screen.At(WindowWidth-1, WindowHeight-1) != e.Frame.At(319, 199) {
+		t.Errorf("The bottom right of the screen should be the bottom right of the frame")
+	}
+	if b.Screen.At(0, 0) != 0xFFFFFFFF {
+		t.Errorf("The top left of the screen should be the wall on the minimap. Received: %08x", b.Screen.At(0, 0))
+	}
+}
+
+func TestHeadlessEscapeQuits(t *testing.T) {
+	e, err := New(Config{Width: 160, Height: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewHeadless(100, ScriptedEvent{Frame: 3, Event: Event{Type: KeyDownEvent, Key: KeyEscape}})
+	if err := e.Run(b, false); err != nil {
+		t.Fatal(err)
+	}
+	if b.Frames != 4 {
+		t.Errorf("The game should quit on the 4th frame. Received: %d", b.Frames)
+	}
+}
+
+func TestHeadlessDrawLine(t *testing.T) {
+	b := NewHeadless(1)
+	b.Open("", 10, 10, 10, 10)
+
+	white := color.NRGBA{255, 255, 255, 255}
+	b.DrawLine(8, 1, 2, 4, white)
+	b.DrawLine(-5, 9, 20, 9, white) // clipped to the screen
+
+	for _, p := range [][2]int{{8, 1}, {6, 2}, {4, 3}, {2, 4}, {0, 9}, {9, 9}} {
+		if c := b.Screen.At(p[0], p[1]); c != 0xFFFFFFFF {
+			t.Errorf("%v should be on the line. Received: %08x", p, c)
+		}
+	}
+	if c := b.Screen.At(8, 4); c != 0x000000FF {
+		t.Errorf("8,4 shouldn't be on the line. Received: %08x", c)
+	}
+}
+
+func TestHeadlessMinimapOnABigMap(t *testing.T) {
+	// 128x128 tiles with walls around the edges would be 1638 pixels wide on the minimap
+	const size = 128
+	data := make(LevelData, size)
+	for i := range data {
+		data[i] = make([]int, size)
+		for j := range data[i] {
+			if i == 0 || j == 0 || i == size-1 || j == size-1 {
+				data[i][j] = 1
+			}
+		}
+	}
+	level, _ := json.Marshal(map[string]interface{}{"id": "big", "map": data})
+	path := writeConfig(t, string(level))
+	defer os.Remove(path)
+
+	e, err := New(Config{Level: path, WindowWidth: Int(640), WindowHeight: Int(400)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewHeadless(1)
+	if err := e.Run(b, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// the minimap is clipped and the rest of the screen is the frame
+	width, height := int(MinimapMaxSize*640), int(MinimapMaxSize*400)
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 640; x++ {
+			if x < width && y < height {
+				continue
+			}
+			if b.Screen.At(x, y) != e.Frame.At(x, y) {
+				t.Fatalf("%d, %d should be the frame and not the minimap. Received: %08x", x, y, b.Screen.At(x, y))
+			}
+		}
+	}
+
+	// the player is in the middle of the map so the minimap shows the floor around them and not the walls
+	if c := b.Screen.At(0, 0); c != 0x000000FF {
+		t.Errorf("The top left of the minimap should be the floor. Received: %08x", c)
+	}
+}
@@ -1,40 +1,199 @@
-package main
+package raycaster
 
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"image"
 	"os"
 )
 
 type LevelData [][]int
 
+// validate makes sure the map isn't empty and every row is the same length. The map can be any size.
+func (d LevelData) validate() error {
+	if len(d) == 0 || len(d[0]) == 0 {
+		return fmt.Errorf("the map is empty")
+	}
+	for i, row := range d {
+		if len(row) != len(d[0]) {
+			return fmt.Errorf("row %d has %d tiles but the first row has %d. Every row needs the same number of tiles", i, len(row), len(d[0]))
+		}
+	}
+	return nil
+}
+
 // Level - type
 type Level struct {
 	Data LevelData `json:"map"`
 	ID   string    `json:"id"`
+
+	// Textures maps a tile ID from the map to the name of an image in the images directory
+	// (the file name without the extension) e.g. "1": "redbrick"
+	Textures map[int]string `json:"textures"`
+
+	// Tiles maps a tile ID to its definition for anything that isn't a plain wall e.g. doors
+	Tiles map[int]*Tile `json:"tiles"`
+
+	// Floor and Ceiling are optional grids the same size as the map with a texture ID per tile.
+	// 0 means there is no texture and the flat color is used instead (i.e. no ceiling).
+	Floor   LevelData `json:"floor"`
+	Ceiling LevelData `json:"ceiling"`
+
+	// Fog overrides the DefaultFog settings. Only the fields that are set in the file are replaced.
+	Fog Fog `json:"fog"`
+
+	// Sprites are the static objects in the level e.g. barrels, lamps etc.
+	Sprites []*Sprite `json:"sprites"`
+
+	// Sky is the name of the image used as the sky wherever a tile has no ceiling.
+	// The image wraps around the player so the left and right edges should match up.
+	Sky string `json:"sky"`
+
+	// Lights are baked into a lightmap when the level is loaded. Levels without lights are fully lit.
+	// Ambient is the light everything gets even if no light reaches it. Defaults to black.
+	Lights  []*Light `json:"lights"`
+	Ambient HexColor `json:"ambient"`
+
+	// Portals join pairs of wall faces so the player can see and walk through them
+	Portals []*Portal `json:"portals"`
+
+	// Decals are images on the faces of walls e.g. signs
+	Decals []*Decal `json:"decals"`
+
+	// Shading makes the west and east faces of the walls darker e.g. 0.25 is a quarter darker.
+	// Same as Wolfenstein did so it's easier to tell where the corners are. Defaults to 0 (off).
+	Shading float64 `json:"shading"`
+
+	// Warnings are the problems LoadLevel found and worked around e.g. textures that weren't loaded
+	Warnings []string `json:"-"`
+
+	textures map[string]*image.NRGBA // the loaded textures the names above are looked up in
 }
 
 func (l *Level) At(i, j int) int {
 	return l.Data[i][j]
 }
 
-// Load level from file
-func LoadLevel(filepath string) *Level {
-	var l Level
+// Rows and Cols are the size of the map in tiles. Every row of the map has the same number of columns.
+func (l *Level) Rows() int {
+	return len(l.Data)
+}
+func (l *Level) Cols() int {
+	return len(l.Data[0])
+}
+
+// WallTexture returns the texture for the tile ID. Any ID that isn't in the texture table
+// or points to an image that wasn't loaded gets the placeholder texture.
+func (l *Level) WallTexture(id int) *image.NRGBA {
+	name, ok := l.Textures[id]
+	if !ok {
+		return placeholderTexture
+	}
+	return l.texture(name)
+}
+
+// FaceTexture returns the texture for the face of the tile ID. Faces without their own texture
+// and hits that aren't on a face (e.g. doors) use the texture of the tile ID.
+func (l *Level) FaceTexture(id, face int) *image.NRGBA {
+	if face != faceNone {
+		if name, ok := l.Tile(id).Faces[faceName(face)]; ok {
+			return l.texture(name)
+		}
+	}
+	return l.WallTexture(id)
+}
+
+// Tile returns the definition for the tile ID. IDs without one are plain walls.
+func (l *Level) Tile(id int) *Tile {
+	if t, ok := l.Tiles[id]; ok {
+		return t
+	}
+	return defaultTile
+}
+
+// WallHeight returns the height of the wall for the tile ID in tiles
+func (l *Level) WallHeight(id int) float64 {
+	if h := l.Tile(id).Height; h > 0 {
+		return h
+	}
+	return 1
+}
+
+// DoorFrameTexture returns the texture for the walls next to the door with the tile ID
+func (l *Level) DoorFrameTexture(id int) *image.NRGBA {
+	return l.texture(l.Tile(id).Frame)
+}
+
+// FloorTexture returns the floor texture for the tile at row i and column j or nil if it doesn't have one
+func (l *Level) FloorTexture(i, j int) *image.NRGBA {
+	return l.flatTexture(l.Floor, i, j)
+}
+
+// CeilingTexture returns the ceiling texture for the tile at row i and column j or nil if it doesn't have one
+func (l *Level) CeilingTexture(i, j int) *image.NRGBA {
+	return l.flatTexture(l.Ceiling, i, j)
+}
+
+// SkyTexture returns the sky texture or nil if the level doesn't have one
+func (l *Level) SkyTexture() *image.NRGBA {
+	return l.textures[l.Sky]
+}
+
+// texture returns the loaded texture with the name or the placeholder if there isn't one
+func (l *Level) texture(name string) *image.NRGBA {
+	return textureByName(l.textures, name)
+}
+
+func (l *Level) flatTexture(grid LevelData, i, j int) *image.NRGBA {
+	if i < 0 || i >= len(grid) || j < 0 || j >= len(grid[i]) {
+		return nil
+	}
+	if id := grid[i][j]; id != 0 {
+		return l.WallTexture(id)
+	}
+	return nil
+}
+
+// Load level from file. The textures are the loaded images the level's texture names are looked up in.
+func LoadLevel(filepath string, textures map[string]*image.NRGBA) (*Level, error) {
+	l := Level{Fog: DefaultFog, textures: textures}
 
 	file, err := os.Open(filepath)
 	if err != nil {
-		log.Fatalf("Failed to load level file: %s. Error: %s", filepath, err)
+		return nil, fmt.Errorf("failed to load level file: %s. Error: %s", filepath, err)
 	}
 	defer file.Close()
 
-	// err := json.Unmarshal(file, &l)
 	d := json.NewDecoder(file)
-	d.Decode(&l)
-	if err != nil {
-		log.Fatalf("Couldn't load level: %s. Error: %s", "level-1", err)
+	if err = d.Decode(&l); err != nil {
+		return nil, fmt.Errorf("couldn't load level: %s. Error: %s", filepath, err)
+	}
+	if err = l.Data.validate(); err != nil {
+		return nil, fmt.Errorf("invalid map in level: %s. Error: %s", filepath, err)
 	}
-	fmt.Println(l.ID)
-	return &l
+
+	for id, name := range l.Textures {
+		if _, ok := textures[name]; !ok {
+			l.warn("texture %q for tile %d not found. Using placeholder.", name, id)
+		}
+	}
+	for id, tile := range l.Tiles {
+		for face, name := range tile.Faces {
+			if _, ok := faceNames[face]; !ok {
+				l.warn("tile %d has a texture for an unknown face %q. Use north, south, west or east.", id, face)
+			} else if _, ok := textures[name]; !ok {
+				l.warn("texture %q for the %s face of tile %d not found. Using placeholder.", name, face, id)
+			}
+		}
+	}
+	if _, ok := textures[l.Sky]; l.Sky != "" && !ok {
+		l.warn("sky texture %q not found. Using the ceiling color.", l.Sky)
+	}
+
+	return &l, nil
+}
+
+// warn adds a warning about the level
+func (l *Level) warn(format string, a ...interface{}) {
+	l.Warnings = append(l.Warnings, fmt.Sprintf("Level %s: ", l.ID)+fmt.Sprintf(format, a...))
 }
@@ -0,0 +1,128 @@
+package raycaster
+
+import (
+	"encoding/json"
+	"image"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testLevel = `{
+	"id": "test",
+	"textures": {"1": "redbrick", "2": "missing"},
+	"map": [[1, 2], [3, 0]]
+}`
+
+func TestLevelDataValidate(t *testing.T) {
+	if err := (LevelData{{1, 1, 1}, {1, 0, 1}}).validate(); err != nil {
+		t.Errorf("A 2x3 map should be valid. Received: %s", err)
+	}
+	if err := (LevelData{}).validate(); err == nil {
+		t.Error("An empty map should be invalid")
+	}
+	if err := (LevelData{{1, 1, 1}, {1, 0}}).validate(); err == nil {
+		t.Error("A map with rows of different lengths should be invalid")
+	}
+}
+
+func TestWallTexture(t *testing.T) {
+	redbrick := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	l := Level{textures: map[string]*image.NRGBA{"redbrick": redbrick}}
+	if err := json.Unmarshal([]byte(testLevel), &l); err != nil {
+		t.Fatalf("Failed to decode level: %s", err)
+	}
+
+	if l.WallTexture(1) != redbrick {
+		t.Error("Tile 1 should use the redbrick texture")
+	}
+	if l.WallTexture(2) != placeholderTexture {
+		t.Error("Tile with a missing image should use the placeholder texture")
+	}
+	if l.WallTexture(3) != placeholderTexture {
+		t.Error("Tile without a texture entry should use the placeholder texture")
+	}
+}
+
+func TestLoadLevelWarnings(t *testing.T) {
+	f, err := ioutil.TempFile("", "level*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(testLevel); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	l, err := LoadLevel(f.Name(), map[string]*image.NRGBA{"redbrick": image.NewNRGBA(image.Rect(0, 0, 64, 64))})
+	if err != nil {
+		t.Fatalf("Failed to load the level: %s", err)
+	}
+	if len(l.Warnings) != 1 || !strings.Contains(l.Warnings[0], `"missing"`) {
+		t.Errorf("Expected a warning about the missing texture. Received: %q", l.Warnings)
+	}
+}
+
+func TestFloorAndCeilingTexture(t *testing.T) {
+	redbrick := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	l := Level{
+		textures: map[string]*image.NRGBA{"redbrick": redbrick},
+		Textures: map[int]string{1: "redbrick"},
+		Floor:    LevelData{{1, 1}},
+		Ceiling:  LevelData{{1, 0}},
+	}
+
+	if l.FloorTexture(0, 1) != redbrick {
+		t.Error("Floor tile should use the redbrick texture")
+	}
+	if l.CeilingTexture(0, 1) != nil {
+		t.Error("Tile with a 0 ceiling should not have a ceiling texture")
+	}
+	if l.FloorTexture(5, 5) != nil || l.CeilingTexture(-1, 0) != nil {
+		t.Error("Tiles outside the grid should not have a texture")
+	}
+}
+
+func TestFaceTexture(t *testing.T) {
+	redbrick := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	bluestone := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	l := Level{
+		textures: map[string]*image.NRGBA{"redbrick": redbrick, "bluestone": bluestone},
+		Textures: map[int]string{1: "redbrick"},
+		Tiles:    map[int]*Tile{1: {Faces: map[string]string{"north": "bluestone"}}},
+	}
+
+	if l.FaceTexture(1, faceNorth) != bluestone {
+		t.Error("North face should use its own texture")
+	}
+	if l.FaceTexture(1, faceEast) != redbrick {
+		t.Error("Faces without their own texture should use the tile texture")
+	}
+	if l.FaceTexture(1, faceNone) != redbrick {
+		t.Error("Hits that aren't on a face should use the tile texture")
+	}
+}
+
+func TestStart(t *testing.T) {
+	data := LevelData{
+		{1, 1, 1, 1},
+		{1, 0, 1, 1},
+		{1, 1, 1, 1},
+		{1, 1, 0, 1},
+	}
+
+	// the middle of the map is a wall so the nearest open tile is used
+	gm := NewGameMap(&Level{Data: data})
+	if x, y, _ := gm.start(); x != 1.5*TileSize || y != 1.5*TileSize {
+		t.Errorf("Expected to start on tile 1, 1. Received: %f, %f", x, y)
+	}
+
+	// the middle is open so that's where we start
+	data[2][2] = 0
+	gm = NewGameMap(&Level{Data: data})
+	if x, y, _ := gm.start(); x != 2*TileSize || y != 2*TileSize {
+		t.Errorf("Expected to start in the middle of the map. Received: %f, %f", x, y)
+	}
+}
@@ -0,0 +1,420 @@
+package raycaster
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/kyriacos/colorbuffer"
+)
+
+// MaxLightmapResolution is how many light samples there are along each side of a tile on
+// small maps. Floors get resolution^2 samples per tile and wall faces resolution. Big maps
+// get fewer so the lightmap doesn't go over MaxLightmapSamples. Dynamic lights always use this.
+const MaxLightmapResolution = 16
+
+// MaxLightmapSamples is how many samples the baked lightmap of a level can have
+const MaxLightmapSamples = 1 << 22
+
+// Light is a point light declared in the level. Lights are baked into the lightmap when
+// the level is loaded so they can't move or change.
+type Light struct {
+	X         float64  `json:"x"` // world coordinates (not tiles)
+	Y         float64  `json:"y"`
+	Color     HexColor `json:"color"`
+	Radius    float64  `json:"radius"`    // nothing further away than this gets any light
+	Intensity float64  `json:"intensity"` // 1 lights up things right next to the light to their full brightness
+}
+
+// lightSample is the amount of light on each channel. 256 is full brightness
+// so we can modulate pixels with a shift the same way we do the fog.
+// Nothing gets brighter than MaxBrightness so it fits in a uint16.
+type lightSample struct {
+	r, g, b uint16
+}
+
+// fullBright leaves the pixels as they are. Used for anything outside of the lightmap.
+var fullBright = lightSample{256, 256, 256}
+
+// faceNone is used for hits that aren't on the side of a tile e.g. doors, segments and moving pushwalls
+const faceNone = -1
+
+// Wall faces. Each wall tile has one lightmap per side.
+const (
+	faceNorth = iota // the side facing up on the map (smaller y)
+	faceSouth
+	faceWest
+	faceEast
+	numFaces
+)
+
+// Lightmap holds the light baked for the floor of every tile that can be seen and every wall face
+// next to one. Solid walls are only ever seen from the outside so big maps that are mostly walls
+// don't need much. Ceilings use the same light as the floor under them.
+type Lightmap struct {
+	rows, cols int
+	resolution int // how many samples there are along each side of a tile
+
+	floorAt []int32       // where the samples of each tile start in floor or -1 if it doesn't have any
+	floor   []lightSample // resolution^2 for every tile that has any
+	wallAt  []int32       // where the samples of each face of each tile start in walls or -1
+	walls   []lightSample // resolution for every face that has any
+
+	unlit lightSample // the ambient light for anything that can't be seen
+}
+
+// bakeLightmap calculates the light for every sample from all the lights in the level.
+// Walls in between block the light. Returns nil if the level doesn't have any lights.
+func bakeLightmap(gm *GameMap) *Lightmap {
+	level := gm.Level
+	if len(level.Lights) == 0 {
+		return nil
+	}
+
+	rows, cols := level.Rows(), level.Cols()
+	lm := &Lightmap{
+		rows:    rows,
+		cols:    cols,
+		floorAt: make([]int32, rows*cols),
+		wallAt:  make([]int32, rows*cols*numFaces),
+		unlit:   sampleOf(level.Ambient),
+	}
+
+	// find everything that can be seen first so we know how many samples we need
+	floors, faces := 0, 0
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			lm.floorAt[i*cols+j] = -1
+			if !gm.closed(i, j) {
+				lm.floorAt[i*cols+j] = int32(floors)
+				floors++
+			}
+
+			// a face can only be seen from the tile next to it. Open tiles get them too since pushwalls can stop there.
+			for face, normal := range faceNormals {
+				f := (i*cols+j)*numFaces + face
+				lm.wallAt[f] = -1
+				ni, nj := i+int(normal[1]), j+int(normal[0])
+				if ni >= 0 && ni < rows && nj >= 0 && nj < cols && !gm.closed(ni, nj) {
+					lm.wallAt[f] = int32(faces)
+					faces++
+				}
+			}
+		}
+	}
+
+	res := lightmapResolution(floors, faces)
+	lm.resolution = res
+	lm.floor = make([]lightSample, floors*res*res)
+	lm.walls = make([]lightSample, faces*res)
+
+	step := float64(TileSize) / float64(res)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			if n := lm.floorAt[i*cols+j]; n >= 0 {
+				start := int(n) * res * res
+				lm.floorAt[i*cols+j] = int32(start)
+				for sy := 0; sy < res; sy++ {
+					for sx := 0; sx < res; sx++ {
+						x := float64(j)*TileSize + (float64(sx)+0.5)*step
+						y := float64(i)*TileSize + (float64(sy)+0.5)*step
+						lm.floor[start+sy*res+sx] = gm.lightAt(x, y, 0, 0)
+					}
+				}
+			}
+
+			for face, normal := range faceNormals {
+				f := (i*cols+j)*numFaces + face
+				n := lm.wallAt[f]
+				if n < 0 {
+					continue
+				}
+
+				// the samples sit just outside of the face so the wall itself doesn't block the light
+				const outside = 0.01
+				start := int(n) * res
+				lm.wallAt[f] = int32(start)
+				top, left := float64(i)*TileSize, float64(j)*TileSize
+				for k := 0; k < res; k++ {
+					along := (float64(k) + 0.5) * step
+					var x, y float64
+					switch face {
+					case faceNorth:
+						x, y = left+along, top-outside
+					case faceSouth:
+						x, y = left+along, top+TileSize+outside
+					case faceWest:
+						x, y = left-outside, top+along
+					case faceEast:
+						x, y = left+TileSize+outside, top+along
+					}
+					lm.walls[start+k] = gm.lightAt(x, y, normal[0], normal[1])
+				}
+			}
+		}
+	}
+	return lm
+}
+
+// lightmapResolution returns how many samples to use along each side of a tile for a lightmap
+// with the floors and faces without going over MaxLightmapSamples. It's always a power of two
+// so the samples line up with the texels.
+func lightmapResolution(floors, faces int) int {
+	res := MaxLightmapResolution
+	for res > 1 && floors*res*res+faces*res > MaxLightmapSamples {
+		res /= 2
+	}
+	return res
+}
+
+// lightAt adds up the light from every light that can see the point x, y. nx, ny is the normal
+// of the wall face the point is on so faces turned away from the light are darker. Floors use 0, 0.
+func (gm *GameMap) lightAt(x, y, nx, ny float64) lightSample {
+	ambient := gm.Level.Ambient
+	r, g, b := float64(ambient.R), float64(ambient.G), float64(ambient.B)
+
+	for _, l := range gm.Level.Lights {
+		amount := gm.lightAmount(l, x, y, nx, ny)
+		r += float64(l.Color.R) * amount
+		g += float64(l.Color.G) * amount
+		b += float64(l.Color.B) * amount
+	}
+
+	return lightSample{
+		r: uint16(math.Min(r, 255) * 256 / 255),
+		g: uint16(math.Min(g, 255) * 256 / 255),
+		b: uint16(math.Min(b, 255) * 256 / 255),
+	}
+}
+
+// sampleOf returns the sample for a light of the color at its full brightness
+func sampleOf(c HexColor) lightSample {
+	return lightSample{r: uint16(int(c.R) * 256 / 255), g: uint16(int(c.G) * 256 / 255), b: uint16(int(c.B) * 256 / 255)}
+}
+
+// lightAmount returns how much of the light gets to the point x, y (0 - Intensity).
+// nx, ny is the normal of the wall face the point is on or 0, 0 for the floor.
+func (gm *GameMap) lightAmount(l *Light, x, y, nx, ny float64) float64 {
+	dx, dy := l.X-x, l.Y-y
+	distance := math.Sqrt(dx*dx + dy*dy)
+	if distance >= l.Radius || !gm.lineOfSight(l.X, l.Y, x, y) {
+		return 0
+	}
+
+	falloff := 1 - distance/l.Radius
+	amount := l.Intensity * falloff * falloff
+	if nx != 0 || ny != 0 {
+		if distance == 0 {
+			return 0
+		}
+		amount *= math.Max(0, (dx*nx+dy*ny)/distance)
+	}
+	return amount
+}
+
+// lineOfSight returns true if there aren't any walls that block light between the two points.
+// Same idea as Ray.Cast, we jump from one grid line to the next and check the tile we end up in
+// except we always take the closest of the two instead of doing the horizontal and vertical ones separately.
+func (gm *GameMap) lineOfSight(x1, y1, x2, y2 float64) bool {
+	i, j := int(math.Floor(y1/TileSize)), int(math.Floor(x1/TileSize))
+	endI, endJ := int(math.Floor(y2/TileSize)), int(math.Floor(x2/TileSize))
+	dx, dy := x2-x1, y2-y1
+
+	// how far along the line (0-1) the next vertical and horizontal grid lines are and how far apart they are
+	stepJ, nextX, deltaX := gridStep(x1, dx)
+	stepI, nextY, deltaY := gridStep(y1, dy)
+
+	// every step takes us one tile closer to the end. The last one is the end tile itself which we don't check.
+	for steps := abs(endI-i) + abs(endJ-j); steps > 1; steps-- {
+		if nextX < nextY {
+			j += stepJ
+			nextX += deltaX
+		} else {
+			i += stepI
+			nextY += deltaY
+		}
+		if gm.blocksLight(i, j) {
+			return false
+		}
+	}
+	return true
+}
+
+// gridStep returns which way to step through the grid along one axis, how far along the line
+// (0-1) the first grid line is and how far apart the grid lines are
+func gridStep(start, length float64) (step int, next, delta float64) {
+	if length == 0 {
+		return 0, math.Inf(1), math.Inf(1)
+	}
+	delta = math.Abs(TileSize / length)
+	tile := math.Floor(start / TileSize)
+	if length > 0 {
+		return 1, ((tile+1)*TileSize - start) / length, delta
+	}
+	return -1, (tile*TileSize - start) / length, delta
+}
+
+// blocksLight returns true if the tile at row i and column j stops light from going through.
+// Doors open and close so they don't block anything and neither do walls we can see through or over.
+// Segment tiles are mostly empty so they don't block anything either.
+func (gm *GameMap) blocksLight(i, j int) bool {
+	if i < 0 || i >= len(gm.Level.Data) || j < 0 || j >= len(gm.Level.Data[i]) {
+		return true
+	}
+	content := gm.Level.At(i, j)
+	if content == 0 || gm.IsTransparent(content) || gm.HasSegments(content) || gm.Level.WallHeight(content) < 1 {
+		return false
+	}
+	return gm.Level.Tile(content).Type != TileDoor
+}
+
+// closed returns true if the inside of the tile at row i and column j can never be seen
+// i.e. it's a solid wall that stays where it is and can't be walked into
+func (gm *GameMap) closed(i, j int) bool {
+	if !gm.blocksLight(i, j) {
+		return false
+	}
+	tile := gm.Level.Tile(gm.Level.At(i, j))
+	return tile.Type != TilePushwall && !tile.Passable
+}
+
+func floorIndex(cols, res, i, j, sx, sy int) int {
+	return ((i*cols+j)*res+sy)*res + sx
+}
+
+// floorSample returns the index of the floor sample at the world position x, y on a map with
+// rows and cols with res samples along each side of a tile or false if it's outside of the map
+func floorSample(x, y float64, rows, cols, res int) (int, bool) {
+	i, j := int(math.Floor(y/TileSize)), int(math.Floor(x/TileSize))
+	if i < 0 || i >= rows || j < 0 || j >= cols {
+		return 0, false
+	}
+	sx, sy := sampleInTile(x, y, i, j, res)
+	return floorIndex(cols, res, i, j, sx, sy), true
+}
+
+// sampleInTile returns which sample of the tile at row i and column j the world position x, y is on
+func sampleInTile(x, y float64, i, j, res int) (sx, sy int) {
+	return int(x-float64(j)*TileSize) * res / TileSize, int(y-float64(i)*TileSize) * res / TileSize
+}
+
+// FloorLight returns the light on the floor (and ceiling) at the world position x, y
+func (lm *Lightmap) FloorLight(x, y float64) lightSample {
+	if lm == nil {
+		return fullBright
+	}
+	i, j := int(math.Floor(y/TileSize)), int(math.Floor(x/TileSize))
+	if i < 0 || i >= lm.rows || j < 0 || j >= lm.cols {
+		return fullBright
+	}
+	if start := lm.floorAt[i*lm.cols+j]; start >= 0 {
+		sx, sy := sampleInTile(x, y, i, j, lm.resolution)
+		return lm.floor[int(start)+sy*lm.resolution+sx]
+	}
+
+	// the floor right at the bottom of a wall can end up just inside of it so use the floor
+	// across the closest edge of the wall
+	left, top := float64(j)*TileSize, float64(i)*TileSize
+	toLeft, toRight, toTop, toBottom := x-left, left+TileSize-x, y-top, top+TileSize-y
+	switch {
+	case toLeft <= toRight && toLeft <= toTop && toLeft <= toBottom:
+		x = left - 1
+	case toRight <= toTop && toRight <= toBottom:
+		x = left + TileSize
+	case toTop <= toBottom:
+		y = top - 1
+	default:
+		y = top + TileSize
+	}
+	if light, ok := lm.floorSample(x, y); ok {
+		return light
+	}
+	return lm.unlit
+}
+
+// floorSample returns the light on the floor at the world position x, y or false if there isn't any there
+func (lm *Lightmap) floorSample(x, y float64) (lightSample, bool) {
+	i, j := int(math.Floor(y/TileSize)), int(math.Floor(x/TileSize))
+	if i < 0 || i >= lm.rows || j < 0 || j >= lm.cols || lm.floorAt[i*lm.cols+j] < 0 {
+		return lightSample{}, false
+	}
+	sx, sy := sampleInTile(x, y, i, j, lm.resolution)
+	return lm.floor[int(lm.floorAt[i*lm.cols+j])+sy*lm.resolution+sx], true
+}
+
+// faceNormals points out of each wall face
+var faceNormals = [numFaces][2]float64{
+	faceNorth: {0, -1},
+	faceSouth: {0, 1},
+	faceWest:  {-1, 0},
+	faceEast:  {1, 0},
+}
+
+// wallFace returns which face of which tile the ray hit. Walls are hit on a grid line so the
+// tile is on one side of it or the other depending on the face. Anything else (doors, moving pushwalls)
+// isn't on the side of a tile and returns faceNone.
+func wallFace(hit *wallHit) (face, i, j int) {
+	switch hit.face {
+	case faceNorth:
+		return faceNorth, int(hit.y / TileSize), int(math.Floor(hit.x / TileSize))
+	case faceSouth:
+		return faceSouth, int(hit.y/TileSize) - 1, int(math.Floor(hit.x / TileSize))
+	case faceWest:
+		return faceWest, int(math.Floor(hit.y / TileSize)), int(hit.x / TileSize)
+	case faceEast:
+		return faceEast, int(math.Floor(hit.y / TileSize)), int(hit.x/TileSize) - 1
+	}
+	return faceNone, 0, 0
+}
+
+// WallLight returns the light on the wall where the ray hit it. Anything that isn't
+// on a wall face uses the light of the floor under it.
+func (lm *Lightmap) WallLight(hit *wallHit) lightSample {
+	if lm == nil {
+		return fullBright
+	}
+
+	face, i, j := wallFace(hit)
+	if face < 0 || i < 0 || i >= lm.rows || j < 0 || j >= lm.cols {
+		return lm.FloorLight(hit.x, hit.y)
+	}
+
+	k := int(hit.offset) * lm.resolution / TileSize
+	if k >= lm.resolution {
+		k = lm.resolution - 1
+	}
+	start := lm.wallAt[(i*lm.cols+j)*numFaces+face]
+	if start < 0 {
+		return lm.unlit
+	}
+	return lm.walls[int(start)+k]
+}
+
+// apply returns the color lit by the light
+func (l lightSample) apply(c color.NRGBA) color.NRGBA {
+	c.R = lightChannel(c.R, l.r)
+	c.G = lightChannel(c.G, l.g)
+	c.B = lightChannel(c.B, l.b)
+	return c
+}
+
+// lightPixel lights the pixel already in the color buffer
+func lightPixel(cb *colorbuffer.ColorBuffer, column, row int, l lightSample) {
+	if l == fullBright {
+		return
+	}
+	o := cb.PixelOffset(column, row)
+	px := cb.Pixels[o : o+3 : o+3]
+	px[0] = lightChannel(px[0], l.r)
+	px[1] = lightChannel(px[1], l.g)
+	px[2] = lightChannel(px[2], l.b)
+}
+
+// lightChannel multiplies a single color channel with the light. Dynamic lights can make things
+// brighter than full brightness so we have to make sure it doesn't overflow.
+func lightChannel(c uint8, light uint16) uint8 {
+	v := int(c) * int(light) >> 8
+	if v > 255 {
+		return 255
+	}
+	return uint8(v)
+}
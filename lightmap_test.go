@@ -0,0 +1,94 @@
+package raycaster
+
+import "testing"
+
+func TestLightIsBlockedByWalls(t *testing.T) {
+	l := &Level{
+		Data: LevelData{
+			{1, 1, 1, 1, 1, 1},
+			{1, 0, 0, 0, 0, 1},
+			{1, 0, 1, 1, 0, 1},
+			{1, 0, 0, 0, 0, 1},
+			{1, 1, 1, 1, 1, 1},
+		},
+		Ambient: HexColor{R: 16, G: 16, B: 16},
+		Lights: []*Light{
+			{X: 2.5 * TileSize, Y: 1.5 * TileSize, Color: HexColor{R: 255, G: 0, B: 0}, Radius: 4 * TileSize, Intensity: 1},
+		},
+	}
+	gm := NewGameMap(l)
+	lm := gm.lightmap
+
+	lit := lm.FloorLight(3.5*TileSize, 1.5*TileSize)
+	if lit.r <= 16 || lit.g != 16 || lit.b != 16 {
+		t.Errorf("Floor next to the light should be red. Received: %v", lit)
+	}
+
+	// behind the wall only the ambient light gets there
+	if dark := lm.FloorLight(2.5*TileSize, 3.5*TileSize); dark.r != 16 {
+		t.Errorf("Floor behind the wall should only get the ambient light. Received: %v", dark)
+	}
+
+	// the top of the wall in the middle faces the light and the bottom doesn't
+	if north := lm.WallLight(&wallHit{x: 2.5 * TileSize, y: 2 * TileSize, offset: TileSize / 2, face: faceNorth}); north.r <= 16 {
+		t.Errorf("Wall facing the light should be lit. Received: %v", north)
+	}
+	if south := lm.WallLight(&wallHit{x: 2.5 * TileSize, y: 3 * TileSize, offset: TileSize / 2, face: faceSouth}); south.r != 16 {
+		t.Errorf("Wall facing away from the light should only get the ambient light. Received: %v", south)
+	}
+}
+
+func TestNoLightsMeansFullBright(t *testing.T) {
+	gm := NewGameMap(&Level{Data: LevelData{{0}}})
+	if gm.lightmap != nil || gm.lightmap.FloorLight(10, 10) != fullBright {
+		t.Error("Levels without lights should not be lit")
+	}
+}
+
+func TestLightmapOnlyHasWhatCanBeSeen(t *testing.T) {
+	l := &Level{
+		Data: LevelData{
+			{1, 1, 1, 1},
+			{1, 0, 2, 1},
+			{1, 1, 1, 1},
+		},
+		Tiles:   map[int]*Tile{2: {Type: TilePushwall}},
+		Ambient: HexColor{R: 16, G: 16, B: 16},
+		Lights:  []*Light{{X: 1.5 * TileSize, Y: 1.5 * TileSize, Color: HexColor{R: 255}, Radius: 4 * TileSize, Intensity: 1}},
+	}
+	lm := NewGameMap(l).lightmap
+
+	// the open tile and the pushwall have a floor and the 4 faces around each of them can be seen.
+	// The faces of the pushwall towards the walls can't.
+	if floors := len(lm.floor) / (lm.resolution * lm.resolution); floors != 2 {
+		t.Errorf("Expected the floor of 2 tiles. Received: %d", floors)
+	}
+	if faces := len(lm.walls) / lm.resolution; faces != 8 {
+		t.Errorf("Expected 8 faces. Received: %d", faces)
+	}
+
+	if light := lm.FloorLight(0.5*TileSize, 0.5*TileSize); light != sampleOf(l.Ambient) {
+		t.Errorf("Inside a wall should only get the ambient light. Received: %v", light)
+	}
+	if light := lm.WallLight(&wallHit{x: 1.5 * TileSize, y: 1 * TileSize, offset: TileSize / 2, face: faceSouth}); light.r <= 16 {
+		t.Errorf("The wall above the light should be lit. Received: %v", light)
+	}
+}
+
+func TestBigMapsGetFewerLightSamples(t *testing.T) {
+	cases := []struct {
+		floors, faces int
+		resolution    int
+	}{
+		{10, 40, MaxLightmapResolution},
+		{4096, 1024, MaxLightmapResolution},
+		{128 * 128, 4 * 128, 8},
+		{256 * 256, 4 * 256, 4},
+		{MaxLightmapSamples * 2, 0, 1}, // too big for any but there's always at least one
+	}
+	for _, c := range cases {
+		if res := lightmapResolution(c.floors, c.faces); res != c.resolution {
+			t.Errorf("%d floors and %d faces should use %d samples. Received: %d", c.floors, c.faces, c.resolution, res)
+		}
+	}
+}
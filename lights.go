@@ -0,0 +1,181 @@
+package raycaster
+
+import (
+	"errors"
+	"math"
+)
+
+// MaxDynamicLights is how many dynamic lights can be active at the same time. Every light
+// has to be added up for everything it reaches every frame so we keep the number small.
+const MaxDynamicLights = 8
+
+// MaxBrightness is how bright something can get with dynamic lights on top of the baked light.
+// 256 is full brightness so things can get up to twice as bright e.g. in a muzzle flash.
+const MaxBrightness = 512
+
+// ErrTooManyLights is returned by AddLight when there are already MaxDynamicLights active
+var ErrTooManyLights = errors.New("too many dynamic lights")
+
+// AddLight adds a light that can move or change while the game is running e.g. a torch or an explosion.
+// Change the fields of the light it returns to move it or change its color and it's picked up on the next frame.
+// Dynamic lights are added on top of the baked lightmap.
+func (gm *GameMap) AddLight(l Light) (*Light, error) {
+	if len(gm.lights) >= MaxDynamicLights {
+		return nil, ErrTooManyLights
+	}
+	light := &l
+	gm.lights = append(gm.lights, light)
+	return light, nil
+}
+
+// RemoveLight removes a light added with AddLight
+func (gm *GameMap) RemoveLight(light *Light) {
+	for i, l := range gm.lights {
+		if l == light {
+			gm.lights = append(gm.lights[:i], gm.lights[i+1:]...)
+			return
+		}
+	}
+}
+
+// lightPatch is the light from one dynamic light on the floor of the tiles it can reach.
+// The samples have the same layout as the lightmap but only for those tiles.
+type lightPatch struct {
+	top, left  int // the first row and column of tiles
+	rows, cols int
+	samples    []lightSample
+}
+
+// at returns the light on the floor at the world position x, y or false if it's outside of the patch
+func (p *lightPatch) at(x, y float64) (lightSample, bool) {
+	s, ok := floorSample(x-float64(p.left*TileSize), y-float64(p.top*TileSize), p.rows, p.cols, MaxLightmapResolution)
+	if !ok {
+		return lightSample{}, false
+	}
+	return p.samples[s], true
+}
+
+// updateDynamicLights works out the light from every dynamic light for the floor the same way
+// the lightmap is baked. Each light only has samples for the tiles it can reach and they are
+// reused from frame to frame. It's called once every frame before drawing.
+func (gm *GameMap) updateDynamicLights() {
+	rows, cols := gm.Level.Rows(), gm.Level.Cols()
+
+	const res = MaxLightmapResolution
+	const step = TileSize / res
+	for n, l := range gm.lights {
+		p := &gm.dynamicFloor[n]
+		minI, maxI := tileRange(l.Y, l.Radius, rows)
+		minJ, maxJ := tileRange(l.X, l.Radius, cols)
+		p.top, p.left = minI, minJ
+		p.rows, p.cols = maxInt(maxI-minI+1, 0), maxInt(maxJ-minJ+1, 0)
+
+		size := p.rows * p.cols * res * res
+		if cap(p.samples) < size {
+			p.samples = make([]lightSample, size)
+		}
+		p.samples = p.samples[:size]
+
+		for i := 0; i < p.rows; i++ {
+			for j := 0; j < p.cols; j++ {
+				for sy := 0; sy < res; sy++ {
+					for sx := 0; sx < res; sx++ {
+						x := float64(minJ+j)*TileSize + (float64(sx)+0.5)*step
+						y := float64(minI+i)*TileSize + (float64(sy)+0.5)*step
+						s := lightSample{}
+						if amount := gm.lightAmount(l, x, y, 0, 0); amount > 0 {
+							s = s.add(l.Color, amount)
+						}
+						p.samples[floorIndex(p.cols, res, i, j, sx, sy)] = s
+					}
+				}
+			}
+		}
+	}
+	gm.dynamicLit = len(gm.lights)
+}
+
+// tileRange returns the first and last tile a light at the position can reach along one axis
+func tileRange(position, radius float64, tiles int) (min, max int) {
+	min = int(math.Max(0, math.Floor((position-radius)/TileSize)))
+	max = int(math.Min(float64(tiles-1), math.Floor((position+radius)/TileSize)))
+	return min, max
+}
+
+// add returns the sample with the amount of the color added to it without going over MaxBrightness
+func (l lightSample) add(c HexColor, amount float64) lightSample {
+	l.r = brightness(int(l.r) + int(float64(c.R)*amount*256/255))
+	l.g = brightness(int(l.g) + int(float64(c.G)*amount*256/255))
+	l.b = brightness(int(l.b) + int(float64(c.B)*amount*256/255))
+	return l
+}
+
+// plus adds two samples together without going over MaxBrightness
+func (l lightSample) plus(o lightSample) lightSample {
+	return lightSample{
+		r: brightness(int(l.r) + int(o.r)),
+		g: brightness(int(l.g) + int(o.g)),
+		b: brightness(int(l.b) + int(o.b)),
+	}
+}
+
+// scale returns the sample with every channel multiplied by f
+func (l lightSample) scale(f float64) lightSample {
+	return lightSample{
+		r: brightness(int(float64(l.r) * f)),
+		g: brightness(int(float64(l.g) * f)),
+		b: brightness(int(float64(l.b) * f)),
+	}
+}
+
+// brightness keeps a channel between nothing and MaxBrightness
+func brightness(v int) uint16 {
+	return uint16(maxInt(minInt(v, MaxBrightness), 0))
+}
+
+// lit returns true if there's any light to add i.e. the level has lights or there are dynamic ones.
+// Without any everything is drawn at full brightness.
+func (gm *GameMap) lit() bool {
+	return gm.lightmap != nil || gm.dynamicLit > 0
+}
+
+// FloorLight returns the baked and dynamic light on the floor (and ceiling) at x, y
+func (gm *GameMap) FloorLight(x, y float64) lightSample {
+	light := gm.lightmap.FloorLight(x, y)
+	if gm.dynamicLit == 0 {
+		return light
+	}
+
+	dynamic := lightSample{}
+	for n := 0; n < gm.dynamicLit; n++ {
+		if s, ok := gm.dynamicFloor[n].at(x, y); ok {
+			dynamic = dynamic.plus(s)
+		}
+	}
+	return light.plus(dynamic)
+}
+
+// WallLight returns the baked and dynamic light on the wall where the ray hit it.
+// There aren't many wall hits so the dynamic lights are worked out for each one.
+func (gm *GameMap) WallLight(hit *wallHit) lightSample {
+	light := gm.lightmap.WallLight(hit)
+	if gm.dynamicLit == 0 {
+		return light
+	}
+
+	face, _, _ := wallFace(hit)
+	if face < 0 {
+		return gm.FloorLight(hit.x, hit.y)
+	}
+
+	// move the point off the wall so the wall itself doesn't block the light
+	normal := faceNormals[face]
+	x, y := hit.x+normal[0]*0.01, hit.y+normal[1]*0.01
+	dynamic := lightSample{}
+	for _, l := range gm.lights {
+		if amount := gm.lightAmount(l, x, y, normal[0], normal[1]); amount > 0 {
+			dynamic = dynamic.add(l.Color, amount)
+		}
+	}
+	return light.plus(dynamic)
+}
@@ -0,0 +1,78 @@
+package raycaster
+
+import "testing"
+
+func TestAddLightIsCapped(t *testing.T) {
+	gm := NewGameMap(&Level{Data: LevelData{{0}}})
+
+	lights := make([]*Light, 0, MaxDynamicLights)
+	for i := 0; i < MaxDynamicLights; i++ {
+		l, err := gm.AddLight(Light{Radius: TileSize})
+		if err != nil {
+			t.Fatalf("Light %d should have been added. Error: %s", i, err)
+		}
+		lights = append(lights, l)
+	}
+	if _, err := gm.AddLight(Light{}); err != ErrTooManyLights {
+		t.Errorf("Expected ErrTooManyLights. Received: %v", err)
+	}
+
+	gm.RemoveLight(lights[3])
+	if _, err := gm.AddLight(Light{}); err != nil {
+		t.Errorf("Removing a light should make room for another one. Error: %s", err)
+	}
+}
+
+func TestDynamicLightsAddToTheFloor(t *testing.T) {
+	gm := NewGameMap(&Level{
+		Data: LevelData{
+			{0, 0, 1, 0},
+		},
+		Lights:  []*Light{{X: 3.5 * TileSize, Y: 0.5 * TileSize, Radius: TileSize}},
+		Ambient: HexColor{R: 64, G: 64, B: 64},
+	})
+
+	l, _ := gm.AddLight(Light{X: 0.5 * TileSize, Y: 0.5 * TileSize, Color: HexColor{B: 255}, Radius: 2 * TileSize, Intensity: 1})
+	gm.updateDynamicLights()
+	if light := gm.FloorLight(0.5*TileSize, 0.5*TileSize); light.b <= 64 || light.r != 64 {
+		t.Errorf("Floor under the light should be blue. Received: %v", light)
+	}
+
+	// moving it behind the wall
+	l.X = 3.5 * TileSize
+	gm.updateDynamicLights()
+	if light := gm.FloorLight(1.5*TileSize, 0.5*TileSize); light.b != 64 {
+		t.Errorf("Wall should block the light. Received: %v", light)
+	}
+
+	gm.RemoveLight(l)
+	gm.updateDynamicLights()
+	if light := gm.FloorLight(3.5*TileSize, 0.5*TileSize); light.b != gm.lightmap.FloorLight(3.5*TileSize, 0.5*TileSize).b {
+		t.Errorf("Removed light should not light anything. Received: %v", light)
+	}
+}
+
+func TestDynamicLightsOnlyCoverWhatTheyReach(t *testing.T) {
+	gm := NewGameMap(&Level{Data: make(LevelData, 64)})
+	for i := range gm.Level.Data {
+		gm.Level.Data[i] = make([]int, 64)
+	}
+
+	gm.AddLight(Light{X: 10.5 * TileSize, Y: 20.5 * TileSize, Color: HexColor{R: 255}, Radius: TileSize, Intensity: 1})
+	gm.AddLight(Light{X: 11.5 * TileSize, Y: 20.5 * TileSize, Color: HexColor{R: 255}, Radius: TileSize, Intensity: 1})
+	gm.updateDynamicLights()
+
+	p := gm.dynamicFloor[0]
+	if p.top != 19 || p.left != 9 || p.rows != 3 || p.cols != 3 || len(p.samples) != 9*MaxLightmapResolution*MaxLightmapResolution {
+		t.Errorf("Expected samples for the 3x3 tiles around the light. Received: %d, %d %dx%d", p.top, p.left, p.rows, p.cols)
+	}
+
+	// where both lights reach they add up
+	one := gm.dynamicFloor[0].samples[floorIndex(3, MaxLightmapResolution, 1, 1, MaxLightmapResolution-1, 0)]
+	if light := gm.FloorLight(11*TileSize-1, 20*TileSize); light.r <= one.r {
+		t.Errorf("Both lights should add up between them. Received: %v", light)
+	}
+	if light := gm.FloorLight(30*TileSize, 30*TileSize); light != fullBright {
+		t.Errorf("The floor away from the lights should only have the baked light. Received: %v", light)
+	}
+}
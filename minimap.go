@@ -0,0 +1,96 @@
+package raycaster
+
+import (
+	"image/color"
+	"math"
+)
+
+// Minimap is the part of the map that is drawn in the top left corner of the window. Maps that
+// don't fit in MinimapMaxSize of the window only show the part around the player.
+// Everything drawn on it is clipped to its size.
+type Minimap struct {
+	canvas Canvas
+
+	scale         float64 // size of the minimap compared to the map
+	x, y          float64 // top left corner of the part of the map that is shown in map pixels
+	width, height int     // size of the minimap in window pixels
+}
+
+// newMinimap returns the minimap for the map centered on the player at x, y where it fits.
+// The window size is in window pixels.
+func newMinimap(c Canvas, gm *GameMap, scale, x, y float64, windowWidth, windowHeight int) *Minimap {
+	m := &Minimap{canvas: c, scale: scale}
+	m.width, m.x = minimapView(scale, gm.Width(), x, windowWidth)
+	m.height, m.y = minimapView(scale, gm.Height(), y, windowHeight)
+	return m
+}
+
+// minimapView returns how big the minimap is along one axis and where the part of the map
+// it shows starts. size is the size of the map and center where the player is.
+func minimapView(scale, size, center float64, window int) (int, float64) {
+	full, max := minimapScale(scale, size), int(MinimapMaxSize*float64(window))
+	if full <= max || scale <= 0 {
+		return full, 0
+	}
+
+	// keep the player in the middle until the edge of the map is reached
+	shown := float64(max) / scale
+	return max, math.Max(0, math.Min(center-shown/2, size-shown))
+}
+
+// point returns where the point on the map is on the minimap
+func (m *Minimap) point(x, y float64) (int, int) {
+	return minimapScale(m.scale, x-m.x), minimapScale(m.scale, y-m.y)
+}
+
+// drawLine draws the line between the two points on the map
+func (m *Minimap) drawLine(x1, y1, x2, y2 float64, c color.NRGBA) {
+	sx1, sy1 := m.point(x1, y1)
+	sx2, sy2 := m.point(x2, y2)
+	m.DrawLine(sx1, sy1, sx2, sy2, c)
+}
+
+// FillRect fills the part of the rectangle that is on the minimap
+func (m *Minimap) FillRect(x, y, w, h int, c color.NRGBA) {
+	x1, y1 := minInt(x+w, m.width), minInt(y+h, m.height)
+	x, y = maxInt(x, 0), maxInt(y, 0)
+	if x1 <= x || y1 <= y {
+		return
+	}
+	m.canvas.FillRect(x, y, x1-x, y1-y, c)
+}
+
+// DrawLine draws the part of the line that is on the minimap (Liang-Barsky)
+func (m *Minimap) DrawLine(x1, y1, x2, y2 int, c color.NRGBA) {
+	fx, fy := float64(x1), float64(y1)
+	dx, dy := float64(x2-x1), float64(y2-y1)
+	maxX, maxY := float64(m.width-1), float64(m.height-1)
+
+	// how far along the line it enters and leaves the minimap. Each edge gets the direction
+	// of the line across it and the distance to it from the start.
+	start, end := 0.0, 1.0
+	for _, edge := range [4][2]float64{{-dx, fx}, {dx, maxX - fx}, {-dy, fy}, {dy, maxY - fy}} {
+		p, q := edge[0], edge[1]
+		if p == 0 {
+			if q < 0 { // parallel to the edge and outside of it
+				return
+			}
+			continue
+		}
+		t := q / p
+		if p < 0 {
+			start = math.Max(start, t)
+		} else {
+			end = math.Min(end, t)
+		}
+	}
+	if start > end {
+		return
+	}
+
+	m.canvas.DrawLine(
+		int(math.Round(fx+start*dx)), int(math.Round(fy+start*dy)),
+		int(math.Round(fx+end*dx)), int(math.Round(fy+end*dy)),
+		c,
+	)
+}
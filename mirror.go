@@ -0,0 +1,54 @@
+package raycaster
+
+import (
+	"image/color"
+	"math"
+)
+
+// MaxReflections is how many times a ray can bounce off mirrors or go through portals. Two mirrors
+// (or portals) facing each other would go on forever otherwise.
+const MaxReflections = 4
+
+// DefaultMirrorTint is used for mirrors that don't set a tint. A bit of blue so it's easy to tell it's a mirror.
+var DefaultMirrorTint = HexColor{R: 180, G: 200, B: 255, A: 40}
+
+// reflect returns the angle of a ray bouncing off the face of a wall. The north and
+// south faces flip the y direction and the west and east faces flip the x direction.
+func reflect(angle float64, face int) float64 {
+	if face == faceNorth || face == faceSouth {
+		return normalizeAngle(-angle)
+	}
+	return normalizeAngle(PI - angle)
+}
+
+// reflection returns the transform that mirrors points across the grid line the mirror hit is on.
+// Mirrors on the west and east faces reflect across a vertical line and the others across a horizontal one.
+func reflection(hit *wallHit) viewTransform {
+	if hit.face == faceWest || hit.face == faceEast {
+		return viewTransform{xx: -1, yy: 1, dx: 2 * math.Round(hit.x/TileSize) * TileSize}
+	}
+	return viewTransform{xx: 1, yy: -1, dy: 2 * math.Round(hit.y/TileSize) * TileSize}
+}
+
+// mirrorTint returns the color blended on top of what is seen in the mirror
+func (l *Level) mirrorTint(id int) HexColor {
+	if tint := l.Tile(id).Tint; tint != (HexColor{}) {
+		return tint
+	}
+	return DefaultMirrorTint
+}
+
+// tintPixel blends the tint of the mirrors the ray bounced off of to get to the leg on top of
+// the pixel, the furthest first, the same way renderWall does for everything else seen in them
+func (e *Engine) tintPixel(column, row int, ray *Ray, leg int) {
+	level := e.GameMap.Level
+	cosRelative := math.Cos(ray.angle - e.Player.rotationAngle)
+	for l := leg; l > 0; l-- {
+		hit := &ray.hits[ray.legs[l].from]
+		if !e.GameMap.IsMirror(hit.content) {
+			continue
+		}
+		texel := e.GameMap.WallLight(hit).apply(color.NRGBA(level.mirrorTint(hit.content)))
+		blendPixel(e.Frame, column, row, texel, level.Fog.Color, level.Fog.weight(hit.distance*cosRelative))
+	}
+}
@@ -0,0 +1,64 @@
+package raycaster
+
+import (
+	"math"
+	"testing"
+)
+
+func TestReflect(t *testing.T) {
+	tests := []struct {
+		angle    float64
+		face     int
+		expected float64
+	}{
+		{0, faceWest, PI},                   // straight into a wall on the right comes straight back
+		{PI / 4, faceWest, 3 * PI / 4},      // down and right bounces down and left
+		{PI / 4, faceNorth, 7 * PI / 4},     // down and right bounces up and right
+		{5 * PI / 4, faceSouth, 3 * PI / 4}, // up and left bounces down and left
+	}
+
+	for _, test := range tests {
+		if got := reflect(test.angle, test.face); math.Abs(got-test.expected) > 1e-9 {
+			t.Errorf("reflect(%f, %d): Expected %f. Received: %f", test.angle, test.face, test.expected, got)
+		}
+	}
+}
+
+func TestCastBouncesOffMirrors(t *testing.T) {
+	l := &Level{
+		Data: LevelData{
+			{1, 1, 1, 1, 1},
+			{1, 0, 0, 0, 2},
+			{1, 1, 1, 1, 1},
+		},
+		Tiles: map[int]*Tile{
+			2: {Type: TileMirror},
+		},
+	}
+	// the mirror is 2.5 tiles away. The ray comes back and hits the wall behind the player 3 tiles further
+	r := NewRay().Cast(NewGameMap(l), 1.5*TileSize, 1.5*TileSize, 0)
+	if len(r.legs) != 2 || len(r.hits) != 2 {
+		t.Fatalf("Expected 2 legs and 2 hits. Received: %d legs, %d hits", len(r.legs), len(r.hits))
+	}
+	if r.hits[0].content != 2 || r.hits[1].content != 1 {
+		t.Errorf("Expected the mirror and then the wall. Received: %d, %d", r.hits[0].content, r.hits[1].content)
+	}
+	if r.hits[1].distance != 5.5*TileSize {
+		t.Errorf("Expected the reflected wall %d away. Received: %f", 5*TileSize+TileSize/2, r.hits[1].distance)
+	}
+	if x, _ := r.pointAt(4 * TileSize); x != 2.5*TileSize {
+		t.Errorf("Expected the point after the mirror to be on the way back. Received: %f", x)
+	}
+
+	// things in the room show up on the other side of the mirror
+	if x, y := r.legs[1].view.apply(2.5*TileSize, 1.5*TileSize); x != 5.5*TileSize || y != 1.5*TileSize {
+		t.Errorf("Expected the reflection at %d, %d. Received: %f, %f", 5*TileSize+TileSize/2, 3*TileSize/2, x, y)
+	}
+
+	// two mirrors facing each other stop after MaxReflections
+	l.Data[1][0] = 2
+	r.Cast(NewGameMap(l), 1.5*TileSize, 1.5*TileSize, 0)
+	if len(r.legs) != MaxReflections+1 {
+		t.Errorf("Expected %d legs. Received: %d", MaxReflections+1, len(r.legs))
+	}
+}
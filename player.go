@@ -1,9 +1,9 @@
-package main
+package raycaster
 
 import (
+	"fmt"
+	"image/color"
 	"math"
-
-	"github.com/veandco/go-sdl2/sdl"
 )
 
 // Player - stuff
@@ -18,17 +18,28 @@ type Player struct {
 
 	walkSpeed float64
 	turnSpeed float64
+
+	pitch          float64 // how far the horizon is moved up (+) or down (-) in pixels i.e. looking up and down
+	pitchDirection int     // -1 down, +1 up
+	pitchSpeed     float64
+
+	jumpHeight   float64 // how high above the floor the player is while jumping
+	jumpVelocity float64
+	crouching    bool
+
+	torch *Light // dynamic light that follows the player around or nil if it's off
 }
 
-func (p *Player) Render() {
-	Renderer.SetDrawColor(255, 255, 255, 255)
-	rect := &sdl.Rect{
-		X: int32(MinimapScaleFactor * p.x),
-		Y: int32(MinimapScaleFactor * p.y),
-		W: int32(MinimapScaleFactor * p.width),
-		H: int32(MinimapScaleFactor * p.height),
-	}
-	Renderer.FillRect(rect)
+func (p *Player) Render(m *Minimap) {
+	white := color.NRGBA{255, 255, 255, 255}
+	x, y := m.point(p.x, p.y)
+	m.FillRect(
+		x,
+		y,
+		int(m.scale*p.width),
+		int(m.scale*p.height),
+		white,
+	)
 
 	/*
 	 * Add a line to see which angle my player is turning
@@ -41,20 +52,158 @@ func (p *Player) Render() {
 	 *      x
 	 *
 	 */
-	length := 30 * MinimapScaleFactor
-	Renderer.DrawLine(
-		int32(MinimapScaleFactor*p.x),
-		int32(MinimapScaleFactor*p.y),
-		int32(MinimapScaleFactor*p.x+math.Cos(p.rotationAngle)*length),
-		int32(MinimapScaleFactor*p.y+math.Sin(p.rotationAngle)*length),
+	length := 30.0
+	m.drawLine(
+		p.x,
+		p.y,
+		p.x+math.Cos(p.rotationAngle)*length,
+		p.y+math.Sin(p.rotationAngle)*length,
+		white,
 	)
 }
 
-func (p *Player) Update(deltaTime float64) {
-	p.move(deltaTime)
+func (p *Player) Update(gm *GameMap, deltaTime float64) {
+	p.move(gm, deltaTime)
+	if p.torch != nil {
+		p.torch.X, p.torch.Y = p.x, p.y
+	}
+	p.Look(float64(p.pitchDirection) * p.pitchSpeed * deltaTime)
+	p.fall(deltaTime)
+}
+
+// Position returns where the player is and the angle they are looking at
+func (p *Player) Position() (x, y, angle float64) {
+	return p.x, p.y, p.rotationAngle
+}
+
+// Place puts the player at x, y looking at the angle. Nothing checks if there's a wall there.
+func (p *Player) Place(x, y, angle float64) {
+	p.x, p.y, p.rotationAngle = x, y, normalizeAngle(angle)
+}
+
+// Walk starts walking forward (+1) or backward (-1) or stops (0)
+func (p *Player) Walk(direction int) {
+	p.walkDirection = direction
+}
+
+// Turn starts turning right (+1) or left (-1) or stops (0)
+func (p *Player) Turn(direction int) {
+	p.turnDirection = direction
+}
+
+// Tilt starts looking up (+1) or down (-1) or stops (0)
+func (p *Player) Tilt(direction int) {
+	p.pitchDirection = direction
+}
+
+// LookAhead looks straight ahead again
+func (p *Player) LookAhead() {
+	p.pitch = 0
+}
+
+// Crouch lowers the camera while crouching is true
+func (p *Player) Crouch(crouching bool) {
+	p.crouching = crouching
+}
+
+// Look moves the horizon up (+) or down (-) by the amount of pixels
+func (p *Player) Look(amount float64) {
+	p.pitch = math.Max(-MaxPitch, math.Min(MaxPitch, p.pitch+amount))
+}
+
+// Jump only works if the player is standing on the floor
+func (p *Player) Jump() {
+	if p.jumpHeight == 0 {
+		p.jumpVelocity = JumpSpeed
+	}
 }
 
-func (p *Player) move(deltaTime float64) {
+// fall moves the player up or down while jumping and brings them back to the floor
+func (p *Player) fall(deltaTime float64) {
+	if p.jumpHeight == 0 && p.jumpVelocity == 0 {
+		return
+	}
+
+	p.jumpHeight += p.jumpVelocity * deltaTime
+	p.jumpVelocity -= Gravity * deltaTime
+	if p.jumpHeight <= 0 {
+		p.jumpHeight = 0
+		p.jumpVelocity = 0
+	}
+}
+
+// cameraHeight returns how high the camera is from the floor. It can't go through the ceiling.
+func (p *Player) cameraHeight() float64 {
+	h := float64(EyeHeight)
+	if p.crouching {
+		h = CrouchEyeHeight
+	}
+	return math.Min(h+p.jumpHeight, TileSize-1)
+}
+
+// use activates whatever is right in front of the player e.g. opens a door.
+// Returns true if it was a secret i.e. a pushwall that started moving.
+func (p *Player) use(gm *GameMap) bool {
+	x := p.x + math.Cos(p.rotationAngle)*UseDistance
+	y := p.y + math.Sin(p.rotationAngle)*UseDistance
+
+	if door := gm.DoorAt(x, y); door != nil {
+		door.Open()
+		return false
+	}
+
+	return gm.Push(x, y, p.rotationAngle)
+}
+
+// toggleTorch turns the light the player is carrying on or off. It can't be turned on if
+// there are already MaxDynamicLights.
+func (p *Player) toggleTorch(gm *GameMap) error {
+	if p.torch != nil {
+		gm.RemoveLight(p.torch)
+		p.torch = nil
+		return nil
+	}
+
+	torch, err := gm.AddLight(Light{
+		X:         p.x,
+		Y:         p.y,
+		Color:     HexColor{R: 255, G: 200, B: 140, A: 255},
+		Radius:    5 * TileSize,
+		Intensity: 1,
+	})
+	if err != nil {
+		return fmt.Errorf("can't turn on the torch: %s", err)
+	}
+	p.torch = torch
+	return nil
+}
+
+// spray puts a splat at eye level on the wall the ray in the middle of the screen hit.
+// Nothing happens if the ray didn't hit a wall.
+func (p *Player) spray(gm *GameMap, ray *Ray) error {
+	if len(ray.hits) == 0 || ray.hits[0].face == faceNone {
+		return nil
+	}
+	hit := &ray.hits[0]
+	face, i, j := wallFace(hit)
+
+	const size = 0.4 // in tiles
+	down := gm.Level.WallHeight(hit.content) - p.cameraHeight()/TileSize
+	_, err := gm.AddDecal(Decal{
+		TileFace: TileFace{Row: i, Col: j, Face: faceName(face)},
+		Texture:  "splat",
+		X:        faceU(face, hit.offset) - size/2,
+		Y:        down - size/2,
+		Width:    size,
+		Height:   size,
+	})
+	if err != nil {
+		return fmt.Errorf("can't spray there: %s", err)
+	}
+	return nil
+}
+
+func (p *Player) move(gm *GameMap, deltaTime float64) {
 	// Turning: its the turn direction -1/+1/0 multiplied by the rotation speed
 	p.rotationAngle += float64(p.turnDirection) * p.turnSpeed * deltaTime
 
@@ -64,10 +213,18 @@ func (p *Player) move(deltaTime float64) {
 
 	newX := p.x + math.Cos(p.rotationAngle)*moveStep
 	newY := p.y + math.Sin(p.rotationAngle)*moveStep
+	newAngle := p.rotationAngle
+
+	// walking into a portal comes out of the other side facing the way it points
+	if exit := gm.PortalCrossed(p.x, p.y, newX, newY); exit != nil {
+		newX, newY = exit.move.apply(newX, newY)
+		newAngle = normalizeAngle(newAngle + exit.turn)
+	}
 
 	// perform wall collision check
-	if !G.GameMap.HasWallAt(newX, newY) {
+	if !gm.HasWallAt(newX, newY) {
 		p.x = newX
 		p.y = newY
+		p.rotationAngle = newAngle
 	}
 }
@@ -0,0 +1,83 @@
+package raycaster
+
+import "testing"
+
+func TestPlayerLookIsClamped(t *testing.T) {
+	p := &Player{}
+
+	p.Look(MaxPitch * 2)
+	if p.pitch != MaxPitch {
+		t.Errorf("Pitch should be clamped to %d. Received: %f", MaxPitch, p.pitch)
+	}
+	p.Look(-MaxPitch * 3)
+	if p.pitch != -MaxPitch {
+		t.Errorf("Pitch should be clamped to %d. Received: %f", -MaxPitch, p.pitch)
+	}
+}
+
+func TestPlayerJumpLandsBackOnTheFloor(t *testing.T) {
+	p := &Player{}
+
+	p.Jump()
+	p.fall(0.1)
+	if p.jumpHeight <= 0 || p.cameraHeight() <= EyeHeight {
+		t.Errorf("Player should be in the air. Received: %f", p.jumpHeight)
+	}
+
+	for i := 0; i < 100; i++ {
+		p.fall(0.1)
+	}
+	if p.jumpHeight != 0 || p.cameraHeight() != EyeHeight {
+		t.Errorf("Player should be back on the floor. Received: %f", p.jumpHeight)
+	}
+
+	p.crouching = true
+	if p.cameraHeight() != CrouchEyeHeight {
+		t.Errorf("Crouching camera height should be %d. Received: %f", CrouchEyeHeight, p.cameraHeight())
+	}
+}
+
+func TestPlayerUseFindsSecrets(t *testing.T) {
+	gm := NewGameMap(&Level{
+		Data: LevelData{
+			{1, 1, 1, 1, 1},
+			{1, 0, 2, 0, 1},
+			{1, 1, 1, 1, 1},
+		},
+		Tiles: map[int]*Tile{2: {Type: TilePushwall}},
+	})
+	p := &Player{x: 1.5 * TileSize, y: 1.5 * TileSize}
+
+	if !p.use(gm) {
+		t.Error("Pushing the pushwall should find a secret")
+	}
+	if p.use(gm) {
+		t.Error("The same secret shouldn't be found twice")
+	}
+}
+
+func TestPlayerTorchReturnsTheError(t *testing.T) {
+	gm := NewGameMap(&Level{Data: LevelData{{0}}})
+	for i := 0; i < MaxDynamicLights; i++ {
+		gm.AddLight(Light{})
+	}
+
+	p := &Player{x: 0.5 * TileSize, y: 0.5 * TileSize}
+	if err := p.toggleTorch(gm); err == nil || p.torch != nil {
+		t.Errorf("The torch can't be turned on with MaxDynamicLights. Received: %v", err)
+	}
+}
+
+func TestPlayerSprayReturnsTheError(t *testing.T) {
+	gm := NewGameMap(&Level{Data: LevelData{{0, 0}}})
+	p := &Player{x: 0.5 * TileSize, y: 0.5 * TileSize}
+
+	// a hit on a tile that isn't a wall has nowhere to put the splat
+	ray := &Ray{hits: []wallHit{{x: TileSize, y: 0.5 * TileSize, face: faceWest}}}
+	if err := p.spray(gm, ray); err == nil {
+		t.Error("Expected an error spraying a tile that isn't a wall")
+	}
+	if err := p.spray(gm, &Ray{}); err != nil {
+		t.Errorf("Spraying at nothing should do nothing. Received: %s", err)
+	}
+}
@@ -0,0 +1,89 @@
+package raycaster
+
+import "math"
+
+// Portal joins two wall faces anywhere on the map. Looking or walking into one comes out
+// of the other, both ways. The faces don't have to point the same way, whatever goes
+// through is turned so it comes straight out of the other face.
+type Portal struct {
+	A TileFace `json:"a"`
+	B TileFace `json:"b"`
+}
+
+// portalExit moves whatever goes into a portal face out of the face it's paired with
+type portalExit struct {
+	move viewTransform // from the face we went into to the face we came out of
+	back viewTransform // the other way. Used to see what's on the other side.
+	turn float64       // how much the direction changes
+}
+
+// newPortalExit returns the exit for going into the face from and coming out of the face to.
+// Going into a face is going against its normal and coming out is going along the normal of
+// the other face so we turn one into the other and move the center of one face onto the other.
+func newPortalExit(from, to faceKey) *portalExit {
+	in, out := faceNormals[from.face], faceNormals[to.face]
+	// rotate in onto -out. Both are along the axes so cos and sin are exact.
+	cos := -(in[0]*out[0] + in[1]*out[1])
+	sin := -(in[0]*out[1] - in[1]*out[0])
+
+	fromX, fromY := from.center()
+	toX, toY := to.center()
+	move := viewTransform{
+		xx: cos, xy: -sin,
+		yx: sin, yy: cos,
+	}
+	move.dx = toX - (move.xx*fromX + move.xy*fromY)
+	move.dy = toY - (move.yx*fromX + move.yy*fromY)
+
+	return &portalExit{move: move, back: move.inverse(), turn: math.Atan2(sin, cos)}
+}
+
+// addPortals adds both ways of every portal in the level. Portals that don't join two wall faces are skipped.
+func (gm *GameMap) addPortals() {
+	gm.portals = make(map[faceKey]*portalExit)
+	for n, p := range gm.Level.Portals {
+		a, err := p.A.key(gm.Level)
+		if err == nil {
+			var b faceKey
+			if b, err = p.B.key(gm.Level); err == nil {
+				gm.portals[a] = newPortalExit(a, b)
+				gm.portals[b] = newPortalExit(b, a)
+				continue
+			}
+		}
+		gm.warn("portal %d: %s. Skipping it.", n, err)
+	}
+}
+
+// PortalAt returns where the ray comes out if the hit is on a portal or nil if it isn't
+func (gm *GameMap) PortalAt(hit *wallHit) *portalExit {
+	face, i, j := wallFace(hit)
+	if face == faceNone {
+		return nil
+	}
+	return gm.portals[faceKey{i, j, face}]
+}
+
+// PortalCrossed returns the portal the player goes into moving from x1, y1 to x2, y2 or nil if there isn't one
+func (gm *GameMap) PortalCrossed(x1, y1, x2, y2 float64) *portalExit {
+	i1, j1 := int(math.Floor(y1/TileSize)), int(math.Floor(x1/TileSize))
+	i2, j2 := int(math.Floor(y2/TileSize)), int(math.Floor(x2/TileSize))
+
+	if j2 != j1 {
+		face := faceWest // moving right goes into the west face of the next tile
+		if j2 < j1 {
+			face = faceEast
+		}
+		if exit := gm.portals[faceKey{i1, j2, face}]; exit != nil {
+			return exit
+		}
+	}
+	if i2 != i1 {
+		face := faceNorth
+		if i2 < i1 {
+			face = faceSouth
+		}
+		return gm.portals[faceKey{i2, j1, face}]
+	}
+	return nil
+}
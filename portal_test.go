@@ -0,0 +1,78 @@
+package raycaster
+
+import (
+	"math"
+	"testing"
+)
+
+// portalLevel has two rooms that aren't connected except by a portal. The east wall of the first room
+// comes out of the north wall of the second one.
+func portalLevel() *Level {
+	return &Level{
+		Data: LevelData{
+			{1, 1, 1, 1, 1},
+			{1, 0, 0, 0, 1},
+			{1, 1, 1, 1, 1},
+			{1, 1, 1, 1, 1},
+			{1, 0, 0, 0, 1},
+			{1, 0, 0, 0, 1},
+			{1, 1, 1, 1, 1},
+		},
+		Portals: []*Portal{
+			{A: TileFace{Row: 1, Col: 4, Face: "west"}, B: TileFace{Row: 3, Col: 2, Face: "south"}},
+		},
+	}
+}
+
+func TestCastGoesThroughPortals(t *testing.T) {
+	// looking right we go into the portal and come out going down into the second room
+	r := NewRay().Cast(NewGameMap(portalLevel()), 1.5*TileSize, 1.5*TileSize, 0)
+	if len(r.legs) != 2 {
+		t.Fatalf("Expected 2 legs. Received: %d", len(r.legs))
+	}
+	leg := r.legs[1]
+	if leg.x != 2.5*TileSize || leg.y != 4*TileSize || math.Abs(leg.angle-PI/2) > 1e-9 {
+		t.Errorf("Expected the ray to come out at %d, %d going down. Received: %f, %f at %f", 5*TileSize/2, 4*TileSize, leg.x, leg.y, leg.angle)
+	}
+	// 2.5 tiles to the portal and another 2 to the bottom wall of the second room
+	if last := r.hits[len(r.hits)-1]; last.distance != 4.5*TileSize || last.face != faceNorth {
+		t.Errorf("Expected to hit the north face of the bottom wall 4.5 tiles away. Received: %f on face %d", last.distance, last.face)
+	}
+	// a sprite in the second room is seen behind the portal
+	if x, y := leg.view.apply(2.5*TileSize, 5*TileSize); x != 5*TileSize || y != 1.5*TileSize {
+		t.Errorf("Expected the sprite to be seen at %d, %d. Received: %f, %f", 5*TileSize, 3*TileSize/2, x, y)
+	}
+}
+
+func TestPlayerWalksThroughPortals(t *testing.T) {
+	p := &Player{x: 3.9 * TileSize, y: 1.5 * TileSize, walkDirection: 1, walkSpeed: 0.2 * TileSize}
+	gm := NewGameMap(portalLevel())
+
+	p.move(gm, 1)
+	if math.Abs(p.x-2.5*TileSize) > 1e-9 || math.Abs(p.y-4.1*TileSize) > 1e-9 || math.Abs(p.rotationAngle-PI/2) > 1e-9 {
+		t.Errorf("Expected the player in the second room facing down. Received: %f, %f at %f", p.x, p.y, p.rotationAngle)
+	}
+
+	// and back again
+	p.rotationAngle = 1.5 * PI
+	p.move(gm, 1)
+	if math.Abs(p.x-3.9*TileSize) > 1e-9 || math.Abs(p.y-1.5*TileSize) > 1e-9 || math.Abs(p.rotationAngle-PI) > 1e-9 {
+		t.Errorf("Expected the player back in the first room facing left. Received: %f, %f at %f", p.x, p.y, p.rotationAngle)
+	}
+}
+
+func TestInvalidPortalsAreSkipped(t *testing.T) {
+	l := portalLevel()
+	l.Portals = append(l.Portals,
+		&Portal{A: TileFace{Row: 1, Col: 1, Face: "west"}, B: TileFace{Row: 4, Col: 0, Face: "east"}}, // empty tile
+		&Portal{A: TileFace{Row: 1, Col: 0, Face: "up"}, B: TileFace{Row: 4, Col: 0, Face: "east"}},   // not a face
+		&Portal{A: TileFace{Row: 9, Col: 0, Face: "east"}, B: TileFace{Row: 4, Col: 0, Face: "east"}}, // off the map
+	)
+	gm := NewGameMap(l)
+	if len(gm.portals) != 2 {
+		t.Errorf("Expected only both ends of the valid portal. Received: %d", len(gm.portals))
+	}
+	if len(gm.Warnings) != 3 {
+		t.Errorf("Expected a warning for every invalid portal. Received: %q", gm.Warnings)
+	}
+}
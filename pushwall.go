@@ -0,0 +1,94 @@
+package raycaster
+
+import "math"
+
+// Pushwall settings
+const (
+	PushwallSpeed    = 0.75 // tiles per second
+	PushwallDistance = 2    // how many tiles a pushwall moves
+)
+
+// Pushwall is a secret wall that slides away when the player uses it.
+// Once it starts moving it's taken out of the level data and only put back
+// on the grid when it stops so we can draw it in between tiles.
+type Pushwall struct {
+	i, j    int // the tile the pushwall is moving out of
+	content int // the tile ID
+
+	di, dj    int     // direction it's moving in. One of them is always 0
+	offset    float64 // how far it has moved into the next tile (0 - TileSize)
+	tilesLeft int     // tiles left to move after the current one
+}
+
+// bounds returns the box the pushwall currently takes up in world coordinates
+func (pw *Pushwall) bounds() (minX, minY, maxX, maxY float64) {
+	minX = float64(pw.j)*TileSize + float64(pw.dj)*pw.offset
+	minY = float64(pw.i)*TileSize + float64(pw.di)*pw.offset
+	return minX, minY, minX + TileSize, minY + TileSize
+}
+
+// covers returns true if the pushwall is partly in the tile at row i and column j
+func (pw *Pushwall) covers(i, j int) bool {
+	return (i == pw.i && j == pw.j) || (i == pw.i+pw.di && j == pw.j+pw.dj)
+}
+
+// contains returns true if the point x, y is inside the pushwall
+func (pw *Pushwall) contains(x, y float64) bool {
+	minX, minY, maxX, maxY := pw.bounds()
+	return x >= minX && x < maxX && y >= minY && y < maxY
+}
+
+// intersect finds where the ray starting at x, y going in the direction of the angle enters
+// the pushwall. Since it's a box we can use the slab method instead of stepping through the grid:
+// find the distances where the ray enters and leaves the x and y range of the box. The ray is
+// inside the box when it's inside both ranges at the same time.
+func (pw *Pushwall) intersect(x, y, angle float64) (wallHit, bool) {
+	minX, minY, maxX, maxY := pw.bounds()
+	cos, sin := math.Cos(angle), math.Sin(angle)
+
+	// dividing by 0 gives us +/-Inf which works out fine for rays parallel to a side
+	tx1, tx2 := (minX-x)/cos, (maxX-x)/cos
+	ty1, ty2 := (minY-y)/sin, (maxY-y)/sin
+
+	tEnterX, tExitX := math.Min(tx1, tx2), math.Max(tx1, tx2)
+	tEnterY, tExitY := math.Min(ty1, ty2), math.Max(ty1, ty2)
+
+	tEnter := math.Max(tEnterX, tEnterY)
+	tExit := math.Min(tExitX, tExitY)
+	if tEnter > tExit || tEnter <= 0 {
+		return wallHit{}, false
+	}
+
+	hit := wallHit{
+		x:        x + cos*tEnter,
+		y:        y + sin*tEnter,
+		distance: tEnter,
+		vertical: tEnterX > tEnterY, // we went into the x range last so we hit a vertical side
+		content:  pw.content,
+		face:     faceNone,
+	}
+	if hit.vertical {
+		hit.offset = hit.y - minY
+	} else {
+		hit.offset = hit.x - minX
+	}
+	return hit, true
+}
+
+// Update moves the pushwall. Returns true once it has stopped.
+func (pw *Pushwall) Update(deltaTime float64) bool {
+	pw.offset += PushwallSpeed * TileSize * deltaTime
+	for pw.offset >= TileSize {
+		// moved a whole tile
+		pw.offset -= TileSize
+		pw.i += pw.di
+		pw.j += pw.dj
+
+		if pw.tilesLeft == 0 {
+			pw.offset = 0
+			return true
+		}
+		pw.tilesLeft--
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package raycaster
+
+import "testing"
+
+func TestPushwallIntersect(t *testing.T) {
+	pw := &Pushwall{i: 1, j: 1, dj: 1, offset: TileSize / 2}
+
+	// looking right from the middle of the tile to the left of the pushwall
+	hit, ok := pw.intersect(TileSize/2, 1.5*TileSize, 0)
+	if !ok {
+		t.Fatal("Ray should hit the pushwall")
+	}
+	if !hit.vertical || hit.distance != TileSize {
+		t.Errorf("Should hit the vertical side half way into the tile. Received: %+v", hit)
+	}
+	if hit.offset != TileSize/2 {
+		t.Errorf("Texture offset should be in the middle of the face. Received: %f", hit.offset)
+	}
+
+	// looking the other way
+	if _, ok := pw.intersect(TileSize/2, 1.5*TileSize, PI); ok {
+		t.Error("Ray going away from the pushwall should not hit it")
+	}
+}
+
+func TestPush(t *testing.T) {
+	l := &Level{
+		Data: LevelData{
+			{1, 1, 1, 1, 1},
+			{1, 0, 2, 0, 1},
+			{1, 1, 1, 1, 1},
+		},
+		Tiles: map[int]*Tile{2: {Type: TilePushwall}},
+	}
+	gm := NewGameMap(l)
+
+	if found, total := gm.SecretsFound(); found != 0 || total != 1 {
+		t.Errorf("Expected 0/1 secrets found. Received: %d/%d", found, total)
+	}
+
+	// push it to the right. There is only space for 1 tile
+	if !gm.Push(2.5*TileSize, 1.5*TileSize, 0) {
+		t.Fatal("Pushwall should start moving")
+	}
+	if !gm.HasWallAt(2.5*TileSize, 1.5*TileSize) {
+		t.Error("Pushwall should still block its tile while moving")
+	}
+
+	gm.Update(0.5/PushwallSpeed, nil)
+	if !gm.HasWallAt(3.1*TileSize, 1.5*TileSize) || gm.HasWallAt(2.1*TileSize, 1.5*TileSize) {
+		t.Error("Pushwall should be half way into the next tile")
+	}
+
+	gm.Update(1/PushwallSpeed, nil)
+	if len(gm.pushwalls) != 0 || l.At(1, 3) != 2 || l.At(1, 2) != 0 {
+		t.Error("Pushwall should have stopped after one tile since the next one is a wall")
+	}
+	if found, _ := gm.SecretsFound(); found != 1 {
+		t.Error("Secret should be found")
+	}
+	if gm.Push(3.5*TileSize, 1.5*TileSize, PI) {
+		t.Error("Pushwall should only move once")
+	}
+}
@@ -1,16 +1,15 @@
-package main
+package raycaster
 
 import (
+	"image/color"
 	"math"
-
-	"github.com/veandco/go-sdl2/sdl"
 )
 
-// Rays - many rays
-type Rays [NumRays]*Ray
+// Rays - many rays. One for every column.
+type Rays []*Ray
 
-func NewRays() *Rays {
-	r := new(Rays)
+func NewRays(n int) Rays {
+	r := make(Rays, n)
 	for i := range r {
 		r[i] = NewRay()
 	}
@@ -26,6 +25,46 @@ type Ray struct {
 	isRayFacingLeft, isRayFacingRight bool
 
 	wallHitContent int // store the actual content of the wall once we find a hit
+
+	wallHitOffset    float64 // where along the wall (0 - TileSize) we hit. Used for the texture
+	wallHitDoorFrame int     // the tile ID of the door next to the wall we hit or 0 if there isn't one
+
+	// hits holds every wall the ray went through sorted from the closest to the furthest.
+	// Walls shorter than the tallest wall in the level don't stop the ray so taller walls
+	// behind them can still be seen. The wallHit* fields above are the same as the first hit.
+	hits []wallHit
+
+	// segmentTiles are the segment tiles we already checked. Both intersections go through the same tiles.
+	segmentTiles []tileIndex
+
+	// legs are the parts of the ray between mirrors. The first one starts at the player and every
+	// time the ray hits a mirror it bounces off and a new one starts. See Cast.
+	legs []rayLeg
+}
+
+// rayLeg is a straight part of a ray. Its hits are hits[first:] up to the first hit of the next leg.
+type rayLeg struct {
+	x, y, angle float64 // where it starts and which way it's going
+	start       float64 // distance along the whole ray to where it starts
+	first       int     // index of the first hit of the leg
+
+	// from is the index of the hit on the mirror or portal the leg started from or -1 for the first leg
+	from int
+	// view moves the world seen in the leg to where it's seen from the player. See viewTransform.
+	view viewTransform
+}
+
+// wallHit is a single point where the ray hit a wall
+type wallHit struct {
+	x, y, distance float64
+	vertical       bool
+
+	content   int     // the tile ID of the wall
+	offset    float64 // where along the wall (0 - TileSize) we hit. Used for the texture
+	doorFrame int     // the tile ID of the door next to the wall or 0 if there isn't one
+	face      int     // which side of the tile we hit or faceNone if it's not on the side of a tile e.g. doors
+
+	portal *portalExit // where the ray comes out if the face is a portal
 }
 
 // NewRay - constructor
@@ -42,28 +81,143 @@ func NewRay() *Ray {
 		isRayFacingLeft:  false,
 
 		wallHitContent: -1, // set to -1 for debugging in case
+
+		wallHitOffset:    0,
+		wallHitDoorFrame: 0,
+
+		hits:         make([]wallHit, 0, 4),
+		segmentTiles: make([]tileIndex, 0, 4),
+		legs:         make([]rayLeg, 0, 1),
 	}
 }
 
-func (r *Ray) Render(renderer *sdl.Renderer, x, y float64) {
-	renderer.SetDrawColor(255, 0, 0, 30)
-	renderer.DrawLine(
-		int32(MinimapScaleFactor*x),
-		int32(MinimapScaleFactor*y),
-		int32(MinimapScaleFactor*r.wallHitX),
-		int32(MinimapScaleFactor*r.wallHitY),
-	)
+// addHit calculates the distance to the hit along the whole ray and adds it to the list of hits
+func (r *Ray) addHit(leg *rayLeg, hit wallHit) {
+	hit.distance = leg.start + distanceBetweenPoints(leg.x, leg.y, hit.x, hit.y)
+	r.hits = append(r.hits, hit)
 }
 
-func (r *Ray) Cast(angle float64) *Ray {
-	var xIntercept, yIntercept, xStep, yStep float64
+// sortHits sorts the hits of the leg from the closest to the furthest. There are only ever a
+// few hits so a simple insertion sort is enough and doesn't allocate anything.
+func (r *Ray) sortHits(leg *rayLeg) {
+	hits := r.hits[leg.first:]
+	for i := 1; i < len(hits); i++ {
+		for j := i; j > 0 && hits[j].distance < hits[j-1].distance; j-- {
+			hits[j], hits[j-1] = hits[j-1], hits[j]
+		}
+	}
+}
+
+// legAt returns the leg of the ray that is distance away from the player along the ray
+func (r *Ray) legAt(distance float64) *rayLeg {
+	for i := len(r.legs) - 1; i > 0; i-- {
+		if distance >= r.legs[i].start {
+			return &r.legs[i]
+		}
+	}
+	return &r.legs[0]
+}
 
+// pointAt returns the world position that is distance away from the player along the ray.
+// After a mirror or a portal that's somewhere along the leg the ray carried on in.
+func (r *Ray) pointAt(distance float64) (x, y float64) {
+	leg := r.legAt(distance)
+	return leg.x + math.Cos(leg.angle)*(distance-leg.start), leg.y + math.Sin(leg.angle)*(distance-leg.start)
+}
+
+// isInnerFace returns true if the ray went from a see-through tile straight into another one of the
+// same kind e.g. along a fence. The face between them would just add another layer of the same
+// texture so we skip it. Solid walls never show these faces anyway.
+func (gm *GameMap) isInnerFace(content, previous int) bool {
+	return content == previous && gm.IsTransparent(content)
+}
+
+// addSegmentHits intersects the leg with every segment in the segment tile at x, y
+// the first time the ray goes through it
+func (r *Ray) addSegmentHits(gm *GameMap, leg *rayLeg, x, y float64, content int) {
+	idx := tileIndex{int(math.Floor(y / TileSize)), int(math.Floor(x / TileSize))}
+	for _, t := range r.segmentTiles {
+		if t == idx {
+			return
+		}
+	}
+	r.segmentTiles = append(r.segmentTiles, idx)
+
+	for _, s := range gm.Level.Tile(content).Segments {
+		if hit, ok := s.intersect(idx.i, idx.j, leg.x, leg.y, leg.angle); ok {
+			hit.distance += leg.start
+			hit.content = content
+			r.hits = append(r.hits, hit)
+		}
+	}
+}
+
+func (r *Ray) Render(m *Minimap, x, y float64) {
+	m.drawLine(x, y, r.wallHitX, r.wallHitY, color.NRGBA{255, 0, 0, 30})
+}
+
+// Cast finds everything the ray hits. When the ray hits a mirror it bounces off and when it hits
+// a portal it comes out of the other side. Either way it keeps going from there (a new leg) up to
+// MaxReflections times. The distances of the hits are along the whole ray so everything seen in a
+// mirror or a portal is drawn as if it was behind it. The ray starts at x, y on the map.
+func (r *Ray) Cast(gm *GameMap, x, y, angle float64) *Ray {
 	r.angle = normalizeAngle(angle)
+	r.hits = r.hits[:0]
+	r.legs = r.legs[:0]
+
+	leg := rayLeg{x: x, y: y, angle: r.angle, from: -1, view: identityView}
+legs:
+	for {
+		leg.first = len(r.hits)
+		r.castLeg(gm, &leg)
+		r.legs = append(r.legs, leg)
+
+		// the last hit of a leg is always the one that hides everything behind it
+		if len(r.hits) == leg.first || len(r.legs) > MaxReflections {
+			break
+		}
+		last := len(r.hits) - 1
+		hit := &r.hits[last]
+		next := rayLeg{start: hit.distance, from: last}
+		switch {
+		case hit.portal != nil:
+			next.x, next.y = hit.portal.move.apply(hit.x, hit.y)
+			next.angle = normalizeAngle(leg.angle + hit.portal.turn)
+			next.view = leg.view.after(hit.portal.back)
+		case gm.IsMirror(hit.content) && hit.face != faceNone:
+			next.x, next.y = hit.x, hit.y
+			next.angle = reflect(leg.angle, hit.face)
+			next.view = leg.view.after(reflection(hit))
+		default:
+			break legs
+		}
+		leg = next
+	}
+
+	r.setFacing(r.angle)
+
+	// the closest hit is the one we use for everything that only needs one e.g. the minimap
+	closest := wallHit{distance: math.MaxFloat64} // if we didn't get a hit then we basically just set it to a really large value
+	if len(r.hits) > 0 {
+		closest = r.hits[0]
+	}
+	r.wallHitX = closest.x
+	r.wallHitY = closest.y
+	r.distance = closest.distance
+	r.wallHitContent = closest.content
+	r.wallHitOffset = closest.offset
+	r.wallHitDoorFrame = closest.doorFrame
+	r.wasHitVertical = closest.vertical
+
+	return r
+}
 
+// setFacing works out which way the ray is facing for the angle
+func (r *Ray) setFacing(angle float64) {
 	// we have to figure out which way the ray is facing when trying to calculate the intersects.
 	// Math.PI = 180 Degrees
 	r.isRayFacingDown = false
-	if r.angle > 0 && r.angle < PI {
+	if angle > 0 && angle < PI {
 		r.isRayFacingDown = true
 	}
 	r.isRayFacingUp = !r.isRayFacingDown
@@ -71,10 +225,20 @@ func (r *Ray) Cast(angle float64) *Ray {
 	// 0.5 * Math.PI = 90 Degrees
 	// 1.5 * Math.PI = 270 Degrees
 	r.isRayFacingRight = false
-	if r.angle < 0.5*PI || r.angle > 1.5*PI {
+	if angle < 0.5*PI || angle > 1.5*PI {
 		r.isRayFacingRight = true
 	}
 	r.isRayFacingLeft = !r.isRayFacingRight
+}
+
+// castLeg finds the hits for a single leg of the ray and adds them to the hits
+func (r *Ray) castLeg(gm *GameMap, leg *rayLeg) {
+	var xIntercept, yIntercept, xStep, yStep float64
+
+	// the rays stop at the edges of the map
+	width, height := gm.Width(), gm.Height()
+
+	r.setFacing(leg.angle)
 
 	/*
 	 * ================================
@@ -83,10 +247,7 @@ func (r *Ray) Cast(angle float64) *Ray {
 	 *
 	 */
 
-	foundHorizontalWallHit := false
-	horzWallHitX := 0.0
-	horzWallHitY := 0.0
-	horzWallContent := 0
+	r.segmentTiles = r.segmentTiles[:0]
 
 	/* Find the y-coordinate of the closest horizontal grid intersection
 	 * =================================================================
@@ -122,14 +283,14 @@ func (r *Ray) Cast(angle float64) *Ray {
 	 * yIntercept += this.isRayFacingDown ?  TILE_SIZE : 0;
 	 *
 	 */
-	yIntercept = math.Floor(G.Player.y/TileSize) * TileSize // this always gets the 'top' Ay coordinate i.e. ray facing up
-	if r.isRayFacingDown {                                  // else += 0
+	yIntercept = math.Floor(leg.y/TileSize) * TileSize // this always gets the 'top' Ay coordinate i.e. ray facing up
+	if r.isRayFacingDown {                             // else += 0
 		yIntercept += TileSize
 	}
 
 	// Find the x-coordinate of the closest horizontal grid interception
-	// xIntercept = G.Player.x + ((G.Player.y - yIntercept) / Math.tan(this.angle));
-	xIntercept = G.Player.x + ((yIntercept - G.Player.y) / math.Tan(r.angle))
+	// xIntercept = leg.x + ((leg.y - yIntercept) / Math.tan(this.angle));
+	xIntercept = leg.x + ((yIntercept - leg.y) / math.Tan(leg.angle))
 
 	// Calculate the increment xstep and ystep
 	yStep = TileSize
@@ -137,7 +298,7 @@ func (r *Ray) Cast(angle float64) *Ray {
 		yStep *= -1
 	}
 
-	xStep = TileSize / math.Tan(r.angle)
+	xStep = TileSize / math.Tan(leg.angle)
 	if r.isRayFacingLeft && xStep > 0 { // if the xstep is positive but the ray is facing left we invert
 		xStep *= -1
 	}
@@ -147,11 +308,17 @@ func (r *Ray) Cast(angle float64) *Ray {
 	nextHorzTouchX := xIntercept
 	nextHorzTouchY := yIntercept
 
-	// increment xstep and ystep until we find a wall
+	// going down we hit the top (north) side of the walls and going up the bottom (south) side
+	face := faceNorth
+	if r.isRayFacingUp {
+		face = faceSouth
+	}
+
+	// increment xstep and ystep until we find a wall that hides everything behind it
 	for nextHorzTouchX >= 0 &&
-		nextHorzTouchX <= WindowWidth &&
+		nextHorzTouchX < width &&
 		nextHorzTouchY >= 0 &&
-		nextHorzTouchY <= WindowHeight {
+		nextHorzTouchY < height {
 
 		testTouchX := nextHorzTouchX
 		testTouchY := nextHorzTouchY
@@ -163,17 +330,69 @@ func (r *Ray) Cast(angle float64) *Ray {
 			testTouchY = nextHorzTouchY - 1
 		}
 
-		// Found a wall hit
-		if G.GameMap.HasWallAt(testTouchX, testTouchY) {
-			horzWallHitX = nextHorzTouchX
-			horzWallHitY = nextHorzTouchY
-			horzWallContent = G.GameMap.Level.At(int(math.Floor(testTouchY/TileSize)), int(math.Floor(testTouchX/TileSize)))
-			foundHorizontalWallHit = true
-			break
-		} else {
+		// moving pushwalls are checked separately at the end
+		if gm.MovingPushwallAt(testTouchX, testTouchY) != nil {
 			nextHorzTouchX += xStep
 			nextHorzTouchY += yStep
+			continue
+		}
+
+		// Doors sit in the middle of the tile so we step another half a tile to see if we hit it.
+		// Vertical doors are found by the vertical intersection instead so we just go through the tile.
+		if door := gm.DoorAt(testTouchX, testTouchY); door != nil {
+			if !door.vertical {
+				doorHitX := nextHorzTouchX + xStep/2
+				if offset, ok := door.hit(doorHitX); ok {
+					r.addHit(leg, wallHit{
+						x:       doorHitX,
+						y:       nextHorzTouchY + yStep/2,
+						content: door.content,
+						offset:  offset,
+						face:    faceNone,
+					})
+					if gm.BlocksView(door.content) {
+						break
+					}
+				}
+			}
+			nextHorzTouchX += xStep
+			nextHorzTouchY += yStep
+			continue
+		}
+
+		// the walls in segment tiles can be anywhere in the tile so we intersect them separately
+		content := gm.ContentAt(testTouchX, testTouchY)
+		if gm.HasSegments(content) {
+			r.addSegmentHits(gm, leg, testTouchX, testTouchY, content)
+			nextHorzTouchX += xStep
+			nextHorzTouchY += yStep
+			continue
+		}
+
+		// Found a wall hit
+		if content != 0 && !gm.isInnerFace(content, gm.ContentAt(testTouchX, testTouchY-yStep/2)) {
+			hit := wallHit{
+				x:       nextHorzTouchX,
+				y:       nextHorzTouchY,
+				content: content,
+				offset:  math.Mod(nextHorzTouchX, TileSize),
+				face:    face,
+			}
+			// the walls on the sides of a door get the door frame texture. The tile we came from is half a tile back.
+			if door := gm.DoorAt(testTouchX, testTouchY-yStep/2); door != nil {
+				hit.doorFrame = door.content
+			}
+			hit.portal = gm.PortalAt(&hit)
+			r.addHit(leg, hit)
+
+			// anything shorter than the tallest wall in the level could have something taller behind it so we keep going
+			if gm.BlocksView(content) || hit.portal != nil {
+				break
+			}
 		}
+
+		nextHorzTouchX += xStep
+		nextHorzTouchY += yStep
 	}
 
 	/*
@@ -184,20 +403,15 @@ func (r *Ray) Cast(angle float64) *Ray {
 	 *
 	 */
 
-	foundVerticalWallHit := false
-	vertWallHitX := 0.0
-	vertWallHitY := 0.0
-	vertWallContent := 0
-
 	// Find the x-coordinate of the closest vertical grid interception
-	xIntercept = math.Floor(G.Player.x/TileSize) * TileSize
+	xIntercept = math.Floor(leg.x/TileSize) * TileSize
 	if r.isRayFacingRight { // add 32 (tile_size) if facing right
 		xIntercept += TileSize
 	}
 
 	// Find the y-coordinate of the closest vertical grid interception
-	// yIntercept = G.Player.y + ((G.Player.x - xIntercept) * Math.tan(this.angle));
-	yIntercept = G.Player.y + ((xIntercept - G.Player.x) * math.Tan(r.angle))
+	// yIntercept = leg.y + ((leg.x - xIntercept) * Math.tan(this.angle));
+	yIntercept = leg.y + ((xIntercept - leg.x) * math.Tan(leg.angle))
 
 	// Calculate the increment xstep and ystep
 	xStep = TileSize
@@ -205,7 +419,7 @@ func (r *Ray) Cast(angle float64) *Ray {
 		xStep *= -1
 	}
 
-	yStep = TileSize * math.Tan(r.angle)
+	yStep = TileSize * math.Tan(leg.angle)
 	if r.isRayFacingUp && yStep > 0 {
 		yStep *= -1
 	}
@@ -216,11 +430,16 @@ func (r *Ray) Cast(angle float64) *Ray {
 	nextVertTouchX := xIntercept
 	nextVertTouchY := yIntercept
 
-	// increment xstep and ystep until we find a wall
+	face = faceWest
+	if r.isRayFacingLeft {
+		face = faceEast
+	}
+
+	// increment xstep and ystep until we find a wall that hides everything behind it
 	for nextVertTouchX >= 0 &&
-		nextVertTouchX <= WindowWidth &&
+		nextVertTouchX < width &&
 		nextVertTouchY >= 0 &&
-		nextVertTouchY <= WindowHeight {
+		nextVertTouchY < height {
 
 		testTouchX := nextVertTouchX
 		testTouchY := nextVertTouchY
@@ -232,44 +451,81 @@ func (r *Ray) Cast(angle float64) *Ray {
 			testTouchX = nextVertTouchX - 1
 		}
 
-		if G.GameMap.HasWallAt(testTouchX, nextVertTouchY) {
-			vertWallHitX = nextVertTouchX
-			vertWallHitY = nextVertTouchY
-			vertWallContent = G.GameMap.Level.At(int(math.Floor(testTouchY/TileSize)), int(math.Floor(testTouchX/TileSize)))
+		if gm.MovingPushwallAt(testTouchX, testTouchY) != nil {
+			nextVertTouchX += xStep
+			nextVertTouchY += yStep
+			continue
+		}
 
-			foundVerticalWallHit = true
-			break
-		} else {
+		// same as above for vertical doors
+		if door := gm.DoorAt(testTouchX, testTouchY); door != nil {
+			if door.vertical {
+				doorHitY := nextVertTouchY + yStep/2
+				if offset, ok := door.hit(doorHitY); ok {
+					r.addHit(leg, wallHit{
+						x:        nextVertTouchX + xStep/2,
+						y:        doorHitY,
+						vertical: true,
+						content:  door.content,
+						offset:   offset,
+						face:     faceNone,
+					})
+					if gm.BlocksView(door.content) {
+						break
+					}
+				}
+			}
 			nextVertTouchX += xStep
 			nextVertTouchY += yStep
+			continue
 		}
-	}
 
-	// Calculate both horizontal and vertical distances and choose the smallest value
-	horzHitDistance := math.MaxFloat64 // if we didn't get a hit then we basically just set it to a really large value
-	if foundHorizontalWallHit {
-		horzHitDistance = distanceBetweenPoints(G.Player.x, G.Player.y, horzWallHitX, horzWallHitY)
-	}
-	vertHitDistance := math.MaxFloat64 // if we didn't get a hit then we basically just set it to a really large value
-	if foundVerticalWallHit {
-		vertHitDistance = distanceBetweenPoints(G.Player.x, G.Player.y, vertWallHitX, vertWallHitY)
-	}
+		content := gm.ContentAt(testTouchX, testTouchY)
+		if gm.HasSegments(content) {
+			r.addSegmentHits(gm, leg, testTouchX, testTouchY, content)
+			nextVertTouchX += xStep
+			nextVertTouchY += yStep
+			continue
+		}
+
+		if content != 0 && !gm.isInnerFace(content, gm.ContentAt(testTouchX-xStep/2, testTouchY)) {
+			hit := wallHit{
+				x:        nextVertTouchX,
+				y:        nextVertTouchY,
+				vertical: true,
+				content:  content,
+				offset:   math.Mod(nextVertTouchY, TileSize),
+				face:     face,
+			}
+			if door := gm.DoorAt(testTouchX-xStep/2, testTouchY); door != nil {
+				hit.doorFrame = door.content
+			}
+			hit.portal = gm.PortalAt(&hit)
+			r.addHit(leg, hit)
+
+			if gm.BlocksView(content) || hit.portal != nil {
+				break
+			}
+		}
 
-	// Compare the two and store the smallest one
-	if horzHitDistance < vertHitDistance {
-		r.wallHitX = horzWallHitX
-		r.wallHitY = horzWallHitY
-		r.distance = horzHitDistance
-		r.wallHitContent = horzWallContent
-		r.wasHitVertical = false
-	} else {
-		r.wallHitX = vertWallHitX
-		r.wallHitY = vertWallHitY
-		r.distance = vertHitDistance
-		r.wallHitContent = vertWallContent
-		r.wasHitVertical = true
+		nextVertTouchX += xStep
+		nextVertTouchY += yStep
 	}
 
-	return r
+	// A pushwall that is moving isn't lined up with the grid so we can't find it by stepping through
+	// the grid lines. Instead we intersect the ray with its box.
+	if hit, ok := gm.PushwallHit(leg.x, leg.y, leg.angle); ok {
+		hit.distance += leg.start
+		r.hits = append(r.hits, hit)
+	}
 
+	// Both intersections went on past short walls so we put all the hits in order and drop
+	// everything behind the first wall that hides what's behind it
+	r.sortHits(leg)
+	for i := leg.first; i < len(r.hits); i++ {
+		if gm.BlocksView(r.hits[i].content) || r.hits[i].portal != nil {
+			r.hits = r.hits[:i+1]
+			break
+		}
+	}
 }
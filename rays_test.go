@@ -0,0 +1,101 @@
+package raycaster
+
+import "testing"
+
+func TestCastGoesPastShortWalls(t *testing.T) {
+	l := &Level{
+		Data: LevelData{
+			{1, 1, 1, 1, 1, 1},
+			{1, 0, 2, 0, 3, 1},
+			{1, 1, 1, 1, 1, 1},
+		},
+		Tiles: map[int]*Tile{
+			2: {Height: 0.5},
+			3: {Height: 2},
+		},
+	}
+	gm := NewGameMap(l)
+
+	// looking right. we should see the half wall and the tower behind it but not the wall behind the tower
+	r := NewRay().Cast(gm, 1.5*TileSize, 1.5*TileSize, 0)
+	if len(r.hits) != 2 {
+		t.Fatalf("Expected 2 hits. Received: %d", len(r.hits))
+	}
+	if r.hits[0].content != 2 || r.hits[1].content != 3 {
+		t.Errorf("Hits are not sorted from the closest. Received: %d, %d", r.hits[0].content, r.hits[1].content)
+	}
+	if r.wallHitContent != 2 || r.distance != TileSize/2 {
+		t.Errorf("Ray should be set to the closest hit. Received: %d at %f", r.wallHitContent, r.distance)
+	}
+
+	// looking left there's just a normal wall. It's shorter than the tower so we keep going until the edge of the map
+	r.Cast(gm, 1.5*TileSize, 1.5*TileSize, PI)
+	if len(r.hits) != 1 || r.wallHitContent != 1 {
+		t.Errorf("Expected a single hit on the wall. Received: %d hits", len(r.hits))
+	}
+}
+
+func TestCastGoesThroughTransparentWalls(t *testing.T) {
+	l := &Level{
+		Data: LevelData{
+			{1, 1, 1, 1, 1, 1, 1},
+			{1, 0, 2, 2, 0, 3, 1},
+			{1, 1, 1, 1, 1, 1, 1},
+		},
+		Tiles: map[int]*Tile{
+			2: {Transparent: true},
+			3: {Transparent: true, Passable: true},
+		},
+	}
+	gm := NewGameMap(l)
+
+	// the face between the two grates is skipped and the ray stops at the wall at the end
+	r := NewRay().Cast(gm, 1.5*TileSize, 1.5*TileSize, 0)
+	if len(r.hits) != 3 {
+		t.Fatalf("Expected 3 hits. Received: %d", len(r.hits))
+	}
+	if r.hits[0].content != 2 || r.hits[1].content != 3 || r.hits[2].content != 1 {
+		t.Errorf("Unexpected hits. Received: %d, %d, %d", r.hits[0].content, r.hits[1].content, r.hits[2].content)
+	}
+
+	if !gm.HasWallAt(2.5*TileSize, 1.5*TileSize) {
+		t.Error("Transparent tiles should block the player unless they are passable")
+	}
+	if gm.HasWallAt(5.5*TileSize, 1.5*TileSize) {
+		t.Error("Passable tiles should not block the player")
+	}
+}
+
+// a level with the given rows and columns and walls all around it
+func boxLevel(rows, cols int) *Level {
+	data := make(LevelData, rows)
+	for i := range data {
+		data[i] = make([]int, cols)
+		for j := range data[i] {
+			if i == 0 || j == 0 || i == rows-1 || j == cols-1 {
+				data[i][j] = 1
+			}
+		}
+	}
+	return &Level{Data: data}
+}
+
+func TestCastOnMapsOfAnySize(t *testing.T) {
+	for _, size := range [][2]int{{8, 8}, {3, 40}, {256, 256}} {
+		rows, cols := size[0], size[1]
+		gm := NewGameMap(boxLevel(rows, cols))
+
+		// right across the map. The big ones are bigger than the window
+		r := NewRay().Cast(gm, 1.5*TileSize, 1.5*TileSize, 0)
+		if want := float64(cols-2)*TileSize - TileSize/2; r.wallHitContent != 1 || r.distance != want {
+			t.Errorf("%dx%d: the ray should hit the east wall %f away. Received: %d at %f", rows, cols, want, r.wallHitContent, r.distance)
+		}
+
+		if gm.HasWallAt(float64(cols-2)*TileSize, 1.5*TileSize) {
+			t.Errorf("%dx%d: the last tile before the east wall should be empty", rows, cols)
+		}
+		if !gm.HasWallAt(float64(cols)*TileSize, 1.5*TileSize) {
+			t.Errorf("%dx%d: outside of the map should be a wall", rows, cols)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package raycaster
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/kyriacos/colorbuffer"
+)
+
+// newRowDistanceScale is used to find the straight (perpendicular) distance from the player to the point
+// on the floor/ceiling that is visible p rows away from the horizon. It only depends on p
+// so we calculate it once instead of for every pixel.
+//
+//	Similar triangles:
+//	      rowDistance            rowsToProjPlane
+//	   ----------------  =  -------------------------
+//	      eyeHeight                     p
+//
+// so rowDistance = eyeHeight * rowDistanceScale[p]. For the ceiling we use the height of the
+// ceiling above the camera instead. p can be up to the whole height of the screen plus how far
+// the horizon can move.
+// Index 0 is the horizon itself which is infinitely far away so we leave it at 0.
+func (e *Engine) newRowDistanceScale() []float64 {
+	d := make([]float64, e.Frame.Height+e.pitchRows(MaxPitch)+1)
+	for p := 1; p < len(d); p++ {
+		d[p] = e.rowsToProjPlane / float64(p)
+	}
+	return d
+}
+
+func (e *Engine) project3d() {
+	e.horizon = e.Frame.Height/2 + e.pitchRows(e.Player.pitch)
+	e.eyeHeight = e.Player.cameraHeight()
+	e.GameMap.updateDynamicLights()
+
+	fog := &e.GameMap.Level.Fog
+	for p, scale := range e.rowDistanceScale {
+		if p == 0 { // the e.horizon
+			e.fogFloorWeights[p] = fog.weight(math.MaxFloat64)
+			e.fogCeilingWeights[p] = e.fogFloorWeights[p]
+			continue
+		}
+		e.fogFloorWeights[p] = fog.weight(e.eyeHeight * scale)
+		e.fogCeilingWeights[p] = fog.weight((TileSize - e.eyeHeight) * scale)
+	}
+
+	for i, ray := range e.Rays {
+		// used to calculate the perpendicular distance to remove the fisheye effect
+		cosRelative := math.Cos(ray.angle - e.Player.rotationAngle)
+		e.zBuffer[i] = ray.distance * cosRelative
+
+		// Most of the walls behind the closest one are completely hidden so we skip them.
+		// Walls start at the floor so a wall is hidden if its top is below the top of a closer wall.
+		// Whatever is between the bottom of a wall and the top of the wall in front of it is floor
+		// (or ceiling) so we only cast the floor and ceiling where no wall is covering it.
+		// See-through walls don't cover anything so they are drawn on top of whatever is behind them.
+		e.visibleHits = e.visibleHits[:0]
+		coveredFrom := float64(e.Frame.Height)
+		for h := range ray.hits {
+			hit := &ray.hits[h]
+			// portals aren't drawn. Whatever is on the other side is drawn where they are.
+			if hit.portal != nil {
+				continue
+			}
+			top, bottom := e.wallStrip(hit.distance*cosRelative, e.GameMap.Level.WallHeight(hit.content))
+			if top >= coveredFrom {
+				continue
+			}
+
+			e.visibleHits = append(e.visibleHits, hit)
+			// mirrors are drawn on top of their reflection which is after them in the hits
+			if e.GameMap.IsTransparent(hit.content) || e.GameMap.IsMirror(hit.content) {
+				continue
+			}
+			if bottom < coveredFrom {
+				e.renderFloorAndCeiling(i, ray, e.clampRow(bottom), e.clampRow(coveredFrom))
+			}
+			coveredFrom = top
+			if coveredFrom <= 0 {
+				break
+			}
+		}
+		e.renderFloorAndCeiling(i, ray, 0, e.clampRow(coveredFrom))
+
+		// Sprites behind a see-through wall have to be drawn before it so the furthest see-through wall
+		// and everything in front of it waits for the sprites. See drawWallsBehind. Anything seen in a
+		// mirror doesn't wait since the mirror is drawn on top of its reflection anyway.
+		onTop := 0
+		for h, hit := range e.visibleHits {
+			if e.GameMap.IsMirror(hit.content) {
+				break
+			}
+			if e.GameMap.IsTransparent(hit.content) {
+				onTop = h + 1
+			}
+		}
+		e.wallsOnTop[i] = append(e.wallsOnTop[i][:0], e.visibleHits[:onTop]...)
+
+		// draw the walls from the furthest to the closest
+		for h := len(e.visibleHits) - 1; h >= onTop; h-- {
+			e.renderWall(i, e.visibleHits[h], cosRelative)
+		}
+	}
+
+	e.renderSprites()
+
+	// whatever is left is in front of all the sprites
+	for i := range e.wallsOnTop {
+		e.drawWallsBehind(i, 0)
+	}
+}
+
+// drawWallsBehind draws the walls in the column that were left for after the sprites and are
+// further away than the distance. They are drawn from the furthest so everything in the column
+// ends up in the right order as long as the sprites are drawn from the furthest too.
+func (e *Engine) drawWallsBehind(column int, perpendicularDistance float64) {
+	walls := e.wallsOnTop[column]
+	if len(walls) == 0 {
+		return
+	}
+
+	cosRelative := math.Cos(e.Rays[column].angle - e.Player.rotationAngle)
+	for ; len(walls) > 0; walls = walls[:len(walls)-1] {
+		hit := walls[len(walls)-1]
+		if hit.distance*cosRelative < perpendicularDistance {
+			break
+		}
+		e.renderWall(column, hit, cosRelative)
+	}
+	e.wallsOnTop[column] = walls
+}
+
+// wallStrip returns the top and bottom of a wall on the screen. Walls always start at the floor
+// so the bottom is the same no matter how tall the wall is.
+//
+// Anything at the height of the camera is on the horizon. Everything else is moved up or down
+// by how far above or below the camera it is, scaled by the distance.
+func (e *Engine) wallStrip(perpendicularDistance, height float64) (top, bottom float64) {
+	scale := e.rowsToProjPlane / math.Max(perpendicularDistance, MinWallDistance)
+
+	bottom = float64(e.horizon) + e.eyeHeight*scale
+	top = float64(e.horizon) - (height*TileSize-e.eyeHeight)*scale
+	return top, bottom
+}
+
+// clampRow converts y to a row and keeps it on the screen. NaN isn't a row so it's treated as the top.
+func (e *Engine) clampRow(y float64) int {
+	if y < 0 || math.IsNaN(y) {
+		return 0
+	}
+	if y > float64(e.Frame.Height) {
+		return e.Frame.Height
+	}
+	return int(y)
+}
+
+// renderWall draws a single wall strip for the column
+func (e *Engine) renderWall(column int, hit *wallHit, cosRelative float64) {
+	level := e.GameMap.Level
+	fog := &level.Fog
+
+	// calculate perpendicular distance to remove the fisheye effect
+	perpendicularDistance := math.Max(hit.distance*cosRelative, MinWallDistance)
+	projectedWallHeight := (TileSize / perpendicularDistance) * e.rowsToProjPlane
+
+	top, bottom := e.wallStrip(perpendicularDistance, level.WallHeight(hit.content))
+
+	// where the wall starts - starts right after our ceiling
+	wallTopPixel := e.clampRow(top)
+	// ends where the floor starts rendering
+	wallBottomPixel := e.clampRow(bottom)
+	// walls seen in a mirror or a portal can be taller than it
+	if ray := e.Rays[column]; len(ray.legs) > 1 && hit.distance >= ray.legs[1].start {
+		wallTopPixel, wallBottomPixel = e.throughRows(ray, hit.distance, cosRelative, wallTopPixel, wallBottomPixel)
+	}
+
+	// where across the texture we are (0-1). Same for the whole strip
+	u := hit.offset / TileSize
+
+	// pick the texture based on the tile and the face we hit
+	texture := level.FaceTexture(hit.content, hit.face)
+	if hit.doorFrame != 0 {
+		texture = level.DoorFrameTexture(hit.doorFrame)
+	}
+	// the whole strip is the same size on the screen so it uses the same mip level
+	projectedWallWidth := (TileSize / perpendicularDistance) * e.distanceToProjPlane
+	if e.sampling == SamplingMipmap {
+		texture = e.mipLevel(texture, projectedWallWidth, projectedWallHeight)
+	}
+
+	// the whole strip is at the same distance so it gets the same amount of fog
+	fogWeight := fog.weight(perpendicularDistance)
+	transparent := e.GameMap.IsTransparent(hit.content)
+	// and the same light since walls are lit the same all the way up
+	light := e.GameMap.WallLight(hit)
+	if hit.vertical && level.Shading > 0 {
+		light = light.scale(1 - level.Shading)
+	}
+	decals := e.GameMap.DecalsAt(hit)
+	decalU := faceU(hit.face, hit.offset)
+	wallHeight := level.WallHeight(hit.content)
+
+	// the reflection is already drawn so we just tint it
+	if e.GameMap.IsMirror(hit.content) {
+		tint := level.mirrorTint(hit.content)
+		texel := light.apply(color.NRGBA(tint))
+		for y := wallTopPixel; y < wallBottomPixel; y++ {
+			blendPixel(e.Frame, column, y, texel, fog.Color, fogWeight)
+		}
+		return
+	}
+
+	// render the wall from top to bottom - cols
+	for y := wallTopPixel; y < wallBottomPixel; y++ {
+		// how far up the wall we are (in tiles). The texture repeats every tile starting from the floor.
+		tiles := (bottom - (float64(y) + 0.5)) / projectedWallHeight
+		v := 1 - (tiles - math.Floor(tiles))
+
+		texel := sampleTexture(texture, u, v, e.sampling, true)
+		if len(decals) > 0 {
+			texel = e.applyDecals(texel, decals, decalU, wallHeight-tiles, projectedWallWidth, projectedWallHeight)
+		}
+		if transparent && texel.A < 255 {
+			blendPixel(e.Frame, column, y, light.apply(texel), fog.Color, fogWeight)
+			continue
+		}
+		e.Frame.Set(column, y, nrgbaToUint32(texel))
+		lightPixel(e.Frame, column, y, light)
+		fogPixel(e.Frame, column, y, fog.Color, fogWeight)
+	}
+}
+
+// blendPixel blends the texel on top of the pixel already in the color buffer using the texel's alpha.
+// The texel gets its fog first so whatever is behind it isn't fogged twice.
+func blendPixel(cb *colorbuffer.ColorBuffer, column, row int, texel color.NRGBA, fogColor HexColor, fogWeight int) {
+	if texel.A == 0 {
+		return
+	}
+
+	r, g, b := int(texel.R), int(texel.G), int(texel.B)
+	if fogWeight > 0 {
+		r = (r*(256-fogWeight) + int(fogColor.R)*fogWeight) >> 8
+		g = (g*(256-fogWeight) + int(fogColor.G)*fogWeight) >> 8
+		b = (b*(256-fogWeight) + int(fogColor.B)*fogWeight) >> 8
+	}
+
+	a := int(texel.A)
+	o := cb.PixelOffset(column, row)
+	px := cb.Pixels[o : o+3 : o+3]
+	px[0] = uint8((int(px[0])*(255-a) + r*a) / 255)
+	px[1] = uint8((int(px[1])*(255-a) + g*a) / 255)
+	px[2] = uint8((int(px[2])*(255-a) + b*a) / 255)
+}
+
+// renderFloorAndCeiling casts the floor and the ceiling for the rows from-to of a single column.
+// Rows above the horizon are ceiling and rows below it are floor. For every row we find the world
+// position the ray would hit on the floor (or ceiling) and use the tile at that position to pick the texture.
+// Tiles without a texture get the flat colors or the sky for the ceiling if the level has one.
+// Both get the same fog as the walls using the perpendicular distance of each row.
+// The ceiling gets the same light as the floor under it.
+func (e *Engine) renderFloorAndCeiling(column int, ray *Ray, from, to int) {
+	level := e.GameMap.Level
+
+	// the rows from the horizon down are floor and the ones above it are ceiling
+	if from < e.horizon {
+		ceiling := flatSurface{
+			grid:       level.Ceiling,
+			color:      nrgbaToUint32(ColorCeiling),
+			height:     TileSize - e.eyeHeight,
+			fogWeights: e.fogCeilingWeights,
+			sky:        level.SkyTexture(),
+		}
+		e.renderFlat(column, ray, &ceiling, from, minInt(to, e.horizon))
+	}
+	if to > e.horizon {
+		floor := flatSurface{
+			grid:       level.Floor,
+			color:      nrgbaToUint32(ColorFloor),
+			height:     e.eyeHeight,
+			fogWeights: e.fogFloorWeights,
+		}
+		e.renderFlat(column, ray, &floor, maxInt(from, e.horizon), to)
+	}
+}
+
+// flatSurface is the floor or the ceiling
+type flatSurface struct {
+	grid       LevelData    // the texture ID of every tile
+	color      uint32       // for the tiles without a texture
+	height     float64      // how far it is from the camera
+	fogWeights []int        // for every row away from the horizon
+	sky        *image.NRGBA // shown where the ceiling doesn't have a texture. nil for the floor.
+}
+
+// renderFlat draws the rows from-to of a single column of the floor or the ceiling. They are all
+// on the same side of the horizon.
+func (e *Engine) renderFlat(column int, ray *Ray, s *flatSurface, from, to int) {
+	level := e.GameMap.Level
+	fogColor := level.Fog.Color
+
+	// every row is rowDistanceScale[p] away from the player along the ray so the world position
+	// moves by the same step for each of those. The row distances are perpendicular so we undo the
+	// fisheye correction to get the distance along the ray.
+	toRay := s.height / math.Cos(ray.angle-e.Player.rotationAngle)
+	stepX, stepY := math.Cos(ray.angle)*toRay, math.Sin(ray.angle)*toRay
+
+	// anything further away than the first leg of the ray is seen in a mirror or a portal
+	seenThroughFrom := math.Inf(1)
+	if len(ray.legs) > 1 {
+		seenThroughFrom = ray.legs[1].start
+	}
+
+	skyX := 0
+	if s.sky != nil {
+		skyX = skyColumn(s.sky, ray.angle)
+	}
+
+	// the texture of the last tile. Rows next to each other are almost always on the same tile.
+	i, j := math.MinInt32, math.MinInt32
+	var texture *image.NRGBA
+	var mips []*image.NRGBA
+
+	// same for the light of the last light sample. Skipped when there's nothing to light.
+	lit, fogged := e.GameMap.lit(), level.Fog.on()
+	lightX, lightY := math.MinInt32, math.MinInt32
+	light := fullBright
+
+	for y := from; y < to; y++ {
+		// how many rows away from the horizon we are
+		p := y - e.horizon
+		if p < 0 {
+			p = -p
+		}
+		if p == 0 { // exactly on the e.horizon so it's infinitely far away
+			e.Frame.Set(column, y, s.color)
+			if fogged {
+				fogPixel(e.Frame, column, y, fogColor, s.fogWeights[0])
+			}
+			continue
+		}
+
+		// world position seen p rows away from the horizon
+		scale := e.rowDistanceScale[p]
+		x, wy := e.Player.x+stepX*scale, e.Player.y+stepY*scale
+		distance := toRay * scale
+		seenThrough := distance >= seenThroughFrom
+		if seenThrough {
+			x, wy = ray.pointAt(distance)
+		}
+
+		if ti, tj := int(math.Floor(wy/TileSize)), int(math.Floor(x/TileSize)); ti != i || tj != j {
+			i, j = ti, tj
+			texture, mips = nil, nil
+			if i >= 0 && i < len(s.grid) && j >= 0 && j < len(s.grid[i]) && s.grid[i][j] != 0 {
+				texture = level.WallTexture(s.grid[i][j])
+				if e.sampling == SamplingMipmap {
+					mips = e.mipmaps[texture]
+				}
+			}
+		}
+
+		switch {
+		case texture != nil && e.sampling == SamplingNearest:
+			e.copyFlatTexel(column, y, texture, x, wy)
+		case texture != nil:
+			e.filterFlatTexel(column, y, texture, mips, x, wy, s.height*scale)
+		case s.sky != nil:
+			if seenThrough {
+				e.copySkyTexel(column, y, s.sky, skyColumn(s.sky, ray.legAt(distance).angle))
+			} else {
+				e.copySkyTexel(column, y, s.sky, skyX)
+			}
+			continue
+		default:
+			e.Frame.Set(column, y, s.color)
+		}
+		if lit {
+			if lx, ly := int(math.Floor(x*MaxLightmapResolution/TileSize)), int(math.Floor(wy*MaxLightmapResolution/TileSize)); lx != lightX || ly != lightY {
+				lightX, lightY = lx, ly
+				light = e.GameMap.FloorLight(x, wy)
+			}
+			lightPixel(e.Frame, column, y, light)
+		}
+		if fogged {
+			fogPixel(e.Frame, column, y, fogColor, s.fogWeights[p])
+		}
+	}
+}
+
+// filterFlatTexel is the same as copyFlatTexel for the bilinear and mipmap sampling.
+// The mip level is picked from the mips of the texture by how tall a tile would be at the perpendicular distance.
+func (e *Engine) filterFlatTexel(column, row int, texture *image.NRGBA, mips []*image.NRGBA, x, y, perpendicularDistance float64) {
+	if e.sampling == SamplingMipmap {
+		tile := TileSize / perpendicularDistance
+		texture = pickMipLevel(texture, mips, tile*e.distanceToProjPlane, tile*e.rowsToProjPlane)
+	}
+	u, v := x/TileSize-math.Floor(x/TileSize), y/TileSize-math.Floor(y/TileSize)
+	e.Frame.Set(column, row, nrgbaToUint32(sampleTexture(texture, u, v, e.sampling, true)))
+}
+
+// copyFlatTexel copies the texel of a floor/ceiling texture at the world position x, y straight into
+// the color buffer. There are a lot more floor and ceiling pixels than wall pixels so we skip
+// NRGBAAt and ColorBuffer.Set here. Both store the pixel as R, G, B, A bytes so we can just copy them over.
+// x and y are always positive since they are inside a tile on the map. The texture covers the whole tile
+// whatever size it is.
+func (e *Engine) copyFlatTexel(column, row int, texture *image.NRGBA, x, y float64) {
+	b := texture.Rect
+	textureOffsetX := b.Min.X + int(x)%TileSize*b.Dx()/TileSize
+	textureOffsetY := b.Min.Y + int(y)%TileSize*b.Dy()/TileSize
+
+	src := texture.PixOffset(textureOffsetX, textureOffsetY)
+	dst := e.Frame.PixelOffset(column, row)
+	copy(e.Frame.Pixels[dst:dst+4], texture.Pix[src:src+4])
+}
@@ -0,0 +1,113 @@
+package raycaster
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// filled returns a texture with every pixel the color
+func filled(c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, TileSize, TileSize))
+	draw.Draw(img, img.Bounds(), &image.Uniform{c}, image.Point{}, draw.Src)
+	return img
+}
+
+func TestGrateIsDrawnOverTheSpriteBehindIt(t *testing.T) {
+	e, err := New(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// blue bars with holes in between
+	grate := filled(color.NRGBA{})
+	for x := 0; x < TileSize; x += 16 {
+		draw.Draw(grate, image.Rect(x, 0, x+8, TileSize), &image.Uniform{color.NRGBA{0, 0, 255, 255}}, image.Point{}, draw.Src)
+	}
+	e.GameMap = NewGameMap(&Level{
+		Data: LevelData{
+			{1, 1, 1, 1, 1, 1, 1},
+			{1, 0, 2, 0, 0, 0, 1},
+			{1, 1, 1, 1, 1, 1, 1},
+		},
+		Tiles:    map[int]*Tile{2: {Transparent: true}},
+		Textures: map[int]string{1: "wall", 2: "grate"},
+		Sprites:  []*Sprite{{X: 4.5 * TileSize, Y: 1.5 * TileSize, Texture: "barrel"}},
+		textures: map[string]*image.NRGBA{
+			"wall":   filled(color.NRGBA{0, 255, 0, 255}),
+			"grate":  grate,
+			"barrel": filled(color.NRGBA{255, 0, 0, 255}),
+		},
+	})
+	e.Player.x, e.Player.y, e.Player.rotationAngle = 1.5*TileSize, 1.5*TileSize, 0
+	e.Update(0)
+	e.Render()
+
+	const (
+		blue = 0x0000FFFF
+		red  = 0xFF0000FF
+	)
+	// the sprite covers a few columns around the middle of the screen. Every one of them
+	// should show either a bar of the grate or the sprite through a hole.
+	row := e.Frame.Height / 2
+	bars, holes := 0, 0
+	for x := e.Frame.Width/2 - 20; x < e.Frame.Width/2+20; x++ {
+		switch c := e.Frame.At(x, row); c {
+		case blue:
+			bars++
+		case red:
+			holes++
+		default:
+			t.Fatalf("Column %d should be the grate or the sprite. Received: %08x", x, c)
+		}
+	}
+	if bars == 0 || holes == 0 {
+		t.Errorf("Expected the bars of the grate on top of the sprite. Received: %d bars, %d holes", bars, holes)
+	}
+}
+
+func BenchmarkProject3d(b *testing.B) {
+	for _, sampling := range []string{SamplingNearest, SamplingBilinear, SamplingMipmap} {
+		b.Run(sampling, func(b *testing.B) {
+			e, err := New(Config{Sampling: sampling})
+			if err != nil {
+				b.Fatal(err)
+			}
+			e.Update(0)
+
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				e.project3d()
+			}
+		})
+	}
+
+	// without any lights or fog
+	b.Run("plain", func(b *testing.B) {
+		e, err := New(Config{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		e.GameMap.Level.Fog = Fog{Mode: FogNone}
+		e.GameMap.Level.Lights = nil
+		e.GameMap.lightmap = nil
+		e.Update(0)
+
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			e.project3d()
+		}
+	})
+}
+
+func TestRenderRightAgainstAWall(t *testing.T) {
+	e, err := New(Config{Level: "./levels/level1.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// touching the half wall below so the closest hit is at no distance at all
+	e.Player.Place(448, 640, PI/2)
+	e.Update(0)
+	e.Render()
+}
@@ -0,0 +1,30 @@
+package raycaster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ResolutionNative renders at the size of the window
+const ResolutionNative = "native"
+
+// ParseResolution reads a render resolution like 320x200. native is the size of the window.
+// The frame is stretched to fill the window so it doesn't have to be the same size or even the same shape.
+func ParseResolution(s string, windowWidth, windowHeight int) (width, height int, err error) {
+	if s == ResolutionNative {
+		return windowWidth, windowHeight, nil
+	}
+
+	parts := strings.Split(strings.ToLower(s), "x")
+	if len(parts) == 2 {
+		width, err = strconv.Atoi(parts[0])
+		if err == nil {
+			height, err = strconv.Atoi(parts[1])
+		}
+		if err == nil && width > 0 && height > 0 {
+			return width, height, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("invalid resolution %q. Use WIDTHxHEIGHT e.g. 320x200 or %s", s, ResolutionNative)
+}
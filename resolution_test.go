@@ -0,0 +1,19 @@
+package raycaster
+
+import "testing"
+
+func TestParseResolution(t *testing.T) {
+	w, h, err := ParseResolution("320x200", WindowWidth, WindowHeight)
+	if err != nil || w != 320 || h != 200 {
+		t.Errorf("Expected 320x200. Received: %dx%d %v", w, h, err)
+	}
+	w, h, err = ParseResolution(ResolutionNative, 640, 480)
+	if err != nil || w != 640 || h != 480 {
+		t.Errorf("Native should be the size of the window. Received: %dx%d %v", w, h, err)
+	}
+	for _, s := range []string{"", "320", "320x", "x200", "0x200", "-320x200", "320x200x1"} {
+		if _, _, err := ParseResolution(s, WindowWidth, WindowHeight); err == nil {
+			t.Errorf("Resolution %q should be invalid", s)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package raycaster
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Texture sampling modes
+const (
+	SamplingNearest  = "nearest"  // the closest texel. Fast but blocky up close and shimmers in the distance
+	SamplingBilinear = "bilinear" // blends the 4 closest texels
+	SamplingMipmap   = "mipmap"   // bilinear on a smaller version of the texture when it's far away
+)
+
+// validSampling returns an error if the mode isn't one of the sampling modes
+func validSampling(mode string) error {
+	switch mode {
+	case SamplingNearest, SamplingBilinear, SamplingMipmap:
+		return nil
+	}
+	return fmt.Errorf("unknown texture sampling %q. Use %s, %s or %s", mode, SamplingNearest, SamplingBilinear, SamplingMipmap)
+}
+
+// generateMipmaps halves the texture until it's 1x1. Level 0 is the texture itself and every level
+// after that is half the size of the one before it. They are generated for every texture in loadTextures.
+// Every texel is the average of the 4 texels it covers in the level above, weighted by their alpha
+// so transparent texels don't darken the edges.
+func generateMipmaps(img *image.NRGBA) []*image.NRGBA {
+	levels := []*image.NRGBA{img}
+	for prev := img; prev.Bounds().Dx() > 1 || prev.Bounds().Dy() > 1; {
+		w, h := prev.Bounds().Dx(), prev.Bounds().Dy()
+		next := image.NewNRGBA(image.Rect(0, 0, maxInt(w/2, 1), maxInt(h/2, 1)))
+
+		for y := 0; y < next.Rect.Dy(); y++ {
+			for x := 0; x < next.Rect.Dx(); x++ {
+				var r, g, b, a int
+				for dy := 0; dy < 2; dy++ {
+					for dx := 0; dx < 2; dx++ {
+						c := prev.NRGBAAt(prev.Rect.Min.X+minInt(2*x+dx, w-1), prev.Rect.Min.Y+minInt(2*y+dy, h-1))
+						r += int(c.R) * int(c.A)
+						g += int(c.G) * int(c.A)
+						b += int(c.B) * int(c.A)
+						a += int(c.A)
+					}
+				}
+				if a > 0 {
+					next.SetNRGBA(x, y, color.NRGBA{R: uint8(r / a), G: uint8(g / a), B: uint8(b / a), A: uint8(a / 4)})
+				}
+			}
+		}
+
+		levels = append(levels, next)
+		prev = next
+	}
+	return levels
+}
+
+// mipLevel picks the mip level of the texture for a tile that is projectedWidth x projectedHeight pixels on the screen.
+// We want the level where one texel is about one pixel so there's nothing to shimmer. Textures don't have to be
+// square so we go by whichever side has more texels per pixel.
+// Textures without mip levels (e.g. the placeholder) are returned as they are.
+func (e *Engine) mipLevel(texture *image.NRGBA, projectedWidth, projectedHeight float64) *image.NRGBA {
+	return pickMipLevel(texture, e.mipmaps[texture], projectedWidth, projectedHeight)
+}
+
+// pickMipLevel is mipLevel with the mip levels of the texture already looked up
+// for when the same texture is used over and over e.g. the floor of a tile
+func pickMipLevel(texture *image.NRGBA, levels []*image.NRGBA, projectedWidth, projectedHeight float64) *image.NRGBA {
+	if len(levels) == 0 || projectedWidth <= 0 || projectedHeight <= 0 {
+		return texture
+	}
+
+	texelsPerPixel := math.Max(float64(texture.Bounds().Dx())/projectedWidth, float64(texture.Bounds().Dy())/projectedHeight)
+	if texelsPerPixel <= 1 {
+		return texture
+	}
+	level := int(math.Log2(texelsPerPixel))
+	if level >= len(levels) {
+		level = len(levels) - 1
+	}
+	return levels[level]
+}
+
+// sampleTexture returns the color of the texture at u, v (0-1 across the whole texture) using the
+// sampling mode. Pick the mip level with mipLevel before calling it. Walls and floors repeat so
+// we wrap around the edges. Sprites don't so they are clamped to the edges instead.
+func sampleTexture(texture *image.NRGBA, u, v float64, mode string, wrap bool) color.NRGBA {
+	b := texture.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	if mode == SamplingNearest {
+		x, y := int(u*float64(w)), int(v*float64(h))
+		return texture.NRGBAAt(b.Min.X+clampTexel(x, w, wrap), b.Min.Y+clampTexel(y, h, wrap))
+	}
+
+	// texel centers are at .5 so we move back half a texel to find the 4 around the point
+	fx, fy := u*float64(w)-0.5, v*float64(h)-0.5
+	x0, y0 := math.Floor(fx), math.Floor(fy)
+	tx, ty := int((fx-x0)*256), int((fy-y0)*256) // weights of the right and bottom texels (0-256)
+
+	left, right := clampTexel(int(x0), w, wrap), clampTexel(int(x0)+1, w, wrap)
+	top, bottom := clampTexel(int(y0), h, wrap), clampTexel(int(y0)+1, h, wrap)
+
+	pix := texture.Pix
+	tl := pix[texture.PixOffset(b.Min.X+left, b.Min.Y+top):]
+	tr := pix[texture.PixOffset(b.Min.X+right, b.Min.Y+top):]
+	bl := pix[texture.PixOffset(b.Min.X+left, b.Min.Y+bottom):]
+	br := pix[texture.PixOffset(b.Min.X+right, b.Min.Y+bottom):]
+
+	var c [4]uint8
+	for i := range c {
+		t := int(tl[i])*(256-tx) + int(tr[i])*tx
+		bt := int(bl[i])*(256-tx) + int(br[i])*tx
+		c[i] = uint8((t*(256-ty) + bt*ty) >> 16)
+	}
+	return color.NRGBA{R: c[0], G: c[1], B: c[2], A: c[3]}
+}
+
+// clampTexel keeps the texel coordinate inside the texture by wrapping it around or clamping it to the edge
+func clampTexel(t, size int, wrap bool) int {
+	if wrap {
+		t %= size
+		if t < 0 {
+			t += size
+		}
+		return t
+	}
+	if t < 0 {
+		return 0
+	}
+	if t >= size {
+		return size - 1
+	}
+	return t
+}
@@ -0,0 +1,88 @@
+package raycaster
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/kyriacos/colorbuffer"
+)
+
+func TestGenerateMipmaps(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	img.SetNRGBA(0, 0, color.NRGBA{R: 200, A: 255})
+	img.SetNRGBA(1, 0, color.NRGBA{R: 100, A: 255})
+
+	levels := generateMipmaps(img)
+	if len(levels) != 3 {
+		t.Fatalf("Expected 3 levels (4x2, 2x1, 1x1). Received: %d", len(levels))
+	}
+	if size := levels[1].Bounds().Size(); size.X != 2 || size.Y != 1 {
+		t.Errorf("Expected the second level to be 2x1. Received: %v", size)
+	}
+	// the transparent texels shouldn't make it darker
+	if c := levels[1].NRGBAAt(0, 0); c.R != 150 || c.A != 127 {
+		t.Errorf("Expected the average of the opaque texels. Received: %v", c)
+	}
+}
+
+func TestMipLevel(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	e := &Engine{mipmaps: map[*image.NRGBA][]*image.NRGBA{img: generateMipmaps(img)}}
+
+	if mip := e.mipLevel(img, 128, 128); mip != img {
+		t.Error("Close walls should use the full texture")
+	}
+	if mip := e.mipLevel(img, 16, 16); mip.Bounds().Dx() != 16 {
+		t.Errorf("A wall 16 pixels tall should use the 16x16 level. Received: %d", mip.Bounds().Dx())
+	}
+	if mip := e.mipLevel(img, 0.01, 0.01); mip.Bounds().Dx() != 1 {
+		t.Errorf("Tiny walls should use the smallest level. Received: %d", mip.Bounds().Dx())
+	}
+}
+
+func TestMipLevelNonSquare(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 256, 128))
+	e := &Engine{mipmaps: map[*image.NRGBA][]*image.NRGBA{img: generateMipmaps(img)}}
+
+	// 4 texels per pixel across and 2 up and down. It goes by the one with more
+	if mip := e.mipLevel(img, 64, 64); mip.Bounds().Dx() != 64 || mip.Bounds().Dy() != 32 {
+		t.Errorf("Expected the 64x32 level. Received: %v", mip.Bounds().Size())
+	}
+}
+
+func TestCopyFlatTexel(t *testing.T) {
+	e := &Engine{Frame: colorbuffer.NewColorBuffer(1, 1)}
+
+	// the texture covers the whole tile whatever size it is
+	for _, size := range []image.Point{{16, 16}, {64, 64}, {256, 128}} {
+		img := image.NewNRGBA(image.Rect(0, 0, size.X, size.Y))
+		// a quarter of the way across and half way down the tile
+		img.SetNRGBA(size.X/4, size.Y/2, color.NRGBA{R: 255, A: 255})
+
+		e.copyFlatTexel(0, 0, img, 3*TileSize+TileSize/4+0.5, TileSize/2+0.5)
+		if c := e.Frame.At(0, 0); c != 0xFF0000FF {
+			t.Errorf("%v: expected the red texel. Received: %08x", size, c)
+		}
+	}
+}
+
+func TestSampleTexture(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{R: 0, A: 255})
+	img.SetNRGBA(1, 0, color.NRGBA{R: 200, A: 255})
+
+	if c := sampleTexture(img, 0.6, 0.5, SamplingNearest, true); c.R != 200 {
+		t.Errorf("Nearest should pick the closest texel. Received: %v", c)
+	}
+	if c := sampleTexture(img, 0.5, 0.5, SamplingBilinear, false); c.R != 100 {
+		t.Errorf("Bilinear should blend the texels in between. Received: %v", c)
+	}
+	// wrapping around blends the right edge with the left one
+	if c := sampleTexture(img, 1, 0.5, SamplingBilinear, true); c.R != 100 {
+		t.Errorf("Bilinear should wrap around the edge. Received: %v", c)
+	}
+	if c := sampleTexture(img, 1, 0.5, SamplingBilinear, false); c.R != 200 {
+		t.Errorf("Bilinear should clamp to the edge. Received: %v", c)
+	}
+}
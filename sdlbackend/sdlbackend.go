@@ -0,0 +1,181 @@
+// Package sdlbackend runs the raycaster in an SDL window
+package sdlbackend
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/kyriacos/colorbuffer"
+	raycaster "github.com/kyriacos/go-raycaster"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Scaling modes for stretching the rendered frame to the window
+const (
+	ScalingNearest = "nearest" // every pixel becomes a block of pixels like the old games
+	ScalingLinear  = "linear"  // blends the pixels so it's smoother but blurry
+)
+
+// Backend is the SDL window, keyboard, mouse and clock
+type Backend struct {
+	Window   *sdl.Window   // The main window that we render to
+	Renderer *sdl.Renderer // The SDL renderer
+
+	CBTexture *sdl.Texture // The engine's frame is copied into it every tick
+
+	scaling string
+}
+
+// New returns an SDL backend that scales the frames to the window using one of the scaling modes.
+// Nothing is created until the backend is opened.
+func New(scaling string) (*Backend, error) {
+	switch scaling {
+	case ScalingNearest, ScalingLinear:
+		return &Backend{scaling: scaling}, nil
+	}
+	return nil, fmt.Errorf("unknown scaling %q. Use %s or %s", scaling, ScalingNearest, ScalingLinear)
+}
+
+func (b *Backend) Open(title string, width, height, frameWidth, frameHeight int) (err error) {
+	// don't leave half of it open if something fails
+	defer func() {
+		if err != nil {
+			b.Close()
+		}
+	}()
+
+	if err = sdl.Init(sdl.INIT_EVERYTHING); err != nil {
+		return fmt.Errorf("error initializing SDL: %s", err)
+	}
+
+	b.Window, err = sdl.CreateWindow(
+		title,
+		sdl.WINDOWPOS_CENTERED,
+		sdl.WINDOWPOS_CENTERED,
+		int32(width),
+		int32(height),
+		sdl.WINDOW_OPENGL)
+	if err != nil {
+		return fmt.Errorf("error creating SDL window: %s", err)
+	}
+
+	b.Renderer, err = sdl.CreateRenderer(b.Window, -1, sdl.RENDERER_SOFTWARE) // -1 is the default driver (the graphics driver)
+	if err != nil {
+		return fmt.Errorf("error creating SDL renderer: %s", err)
+	}
+
+	if err = b.Renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND); err != nil {
+		return fmt.Errorf("failed to set blend mode: %s", err)
+	}
+
+	// create the texture the frame is copied into at the render resolution. It's stretched to the window in
+	// DrawFrame. The scale quality (same names as the scaling modes) has to be set before it's created.
+	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, b.scaling)
+	b.CBTexture, err = b.Renderer.CreateTexture(
+		sdl.PIXELFORMAT_ABGR8888, // endianess https://forums.libsdl.org/viewtopic.php?p=39284
+		sdl.TEXTUREACCESS_STREAMING,
+		int32(frameWidth),
+		int32(frameHeight),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating the texture: %s", err)
+	}
+
+	return nil
+}
+
+// Close destroys everything Open created. Some of it might not be there if Open failed and
+// it's safe to call more than once.
+func (b *Backend) Close() {
+	// destroy everything in the reverse order it was created
+	if b.CBTexture != nil {
+		b.CBTexture.Destroy()
+		b.CBTexture = nil
+	}
+	if b.Renderer != nil {
+		b.Renderer.Destroy()
+		b.Renderer = nil
+	}
+	if b.Window != nil {
+		b.Window.Destroy()
+		b.Window = nil
+	}
+	sdl.Quit()
+}
+
+func (b *Backend) PollEvent() (raycaster.Event, bool) {
+	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+		switch t := event.(type) {
+		case *sdl.QuitEvent: // sdl.QUIT
+			return raycaster.Event{Type: raycaster.QuitEvent}, true
+		case *sdl.KeyboardEvent:
+			key, ok := keys[t.Keysym.Sym]
+			if !ok {
+				continue
+			}
+			if t.Type == sdl.KEYDOWN {
+				return raycaster.Event{Type: raycaster.KeyDownEvent, Key: key}, true
+			}
+			if t.Type == sdl.KEYUP {
+				return raycaster.Event{Type: raycaster.KeyUpEvent, Key: key}, true
+			}
+		case *sdl.MouseMotionEvent:
+			return raycaster.Event{Type: raycaster.MouseMotionEvent, YRel: int(t.YRel)}, true
+		}
+	}
+	return raycaster.Event{}, false
+}
+
+// keys maps the SDL keys to the ones the game uses. Everything else is ignored.
+var keys = map[sdl.Keycode]raycaster.Key{
+	sdl.K_ESCAPE:   raycaster.KeyEscape,
+	sdl.K_UP:       raycaster.KeyUp,
+	sdl.K_DOWN:     raycaster.KeyDown,
+	sdl.K_LEFT:     raycaster.KeyLeft,
+	sdl.K_RIGHT:    raycaster.KeyRight,
+	sdl.K_SPACE:    raycaster.KeySpace,
+	sdl.K_PAGEUP:   raycaster.KeyPageUp,
+	sdl.K_PAGEDOWN: raycaster.KeyPageDown,
+	sdl.K_END:      raycaster.KeyEnd,
+	sdl.K_c:        raycaster.KeyC,
+	sdl.K_g:        raycaster.KeyG,
+	sdl.K_j:        raycaster.KeyJ,
+	sdl.K_t:        raycaster.KeyT,
+}
+
+func (b *Backend) DrawFrame(frame *colorbuffer.ColorBuffer) {
+	b.Renderer.SetDrawColor(0, 0, 0, 255)
+	b.Renderer.Clear() // clear back buffer
+
+	// update the sdl texture
+	b.CBTexture.Update(nil, frame.Pixels, frame.Stride)
+
+	// copy the texture to the renderer
+	b.Renderer.Copy(b.CBTexture, nil, nil) // nil and nil since we want to use the entire texture (src and dest used if you want to get a subset of the texture) and stretch it to the whole window
+}
+
+func (b *Backend) FillRect(x, y, w, h int, c color.NRGBA) {
+	b.Renderer.SetDrawColor(c.R, c.G, c.B, c.A)
+	b.Renderer.FillRect(&sdl.Rect{X: int32(x), Y: int32(y), W: int32(w), H: int32(h)})
+}
+
+func (b *Backend) DrawLine(x1, y1, x2, y2 int, c color.NRGBA) {
+	b.Renderer.SetDrawColor(c.R, c.G, c.B, c.A)
+	b.Renderer.DrawLine(int32(x1), int32(y1), int32(x2), int32(y2))
+}
+
+func (b *Backend) Present() {
+	// swap current buffer with back buffer
+	b.Renderer.Present()
+}
+
+func (b *Backend) Now() float64 {
+	return float64(sdl.GetPerformanceCounter()) / float64(sdl.GetPerformanceFrequency())
+}
+
+func (b *Backend) Delay(seconds float64) {
+	if seconds > 0 {
+		sdl.Delay(uint32(math.Floor(seconds * 1000)))
+	}
+}
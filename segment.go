@@ -0,0 +1,58 @@
+package raycaster
+
+import "math"
+
+// SegmentThickness is how close the player can get to a segment wall
+const SegmentThickness = 4
+
+// Segment is a wall going from x1, y1 to x2, y2 inside a tile. The coordinates are in tiles
+// (0-1) from the top left corner of the tile e.g. [0, 0, 1, 1] is a diagonal from the top left
+// to the bottom right and [0.5, 0, 0.5, 1] is a thin wall down the middle.
+type Segment [4]float64
+
+// world returns the end points of the segment in world coordinates for the tile at row i and column j
+func (s Segment) world(i, j int) (x1, y1, x2, y2 float64) {
+	left, top := float64(j)*TileSize, float64(i)*TileSize
+	return left + s[0]*TileSize, top + s[1]*TileSize, left + s[2]*TileSize, top + s[3]*TileSize
+}
+
+// intersect finds where the ray starting at x, y going in the direction of the angle hits the
+// segment in the tile at row i and column j. The texture goes along the segment starting from
+// its first point and repeats every tile so longer segments (e.g. diagonals) aren't stretched.
+func (s Segment) intersect(i, j int, x, y, angle float64) (wallHit, bool) {
+	x1, y1, x2, y2 := s.world(i, j)
+	cos, sin := math.Cos(angle), math.Sin(angle)
+	sx, sy := x2-x1, y2-y1
+
+	// solve x + t*cos = x1 + u*sx and y + t*sin = y1 + u*sy for t (along the ray) and u (along the segment)
+	denominator := cos*sy - sin*sx
+	if denominator == 0 { // parallel
+		return wallHit{}, false
+	}
+	t := ((x1-x)*sy - (y1-y)*sx) / denominator
+	u := ((x1-x)*sin - (y1-y)*cos) / denominator
+	if t <= 0 || u < 0 || u > 1 {
+		return wallHit{}, false
+	}
+
+	return wallHit{
+		x:        x + cos*t,
+		y:        y + sin*t,
+		distance: t,
+		offset:   math.Mod(u*math.Sqrt(sx*sx+sy*sy), TileSize),
+		face:     faceNone,
+	}, true
+}
+
+// distanceTo returns the shortest distance from the point x, y to the segment in the tile at row i and column j
+func (s Segment) distanceTo(i, j int, x, y float64) float64 {
+	x1, y1, x2, y2 := s.world(i, j)
+	sx, sy := x2-x1, y2-y1
+
+	// how far along the segment (0-1) the closest point is
+	u := 0.0
+	if length := sx*sx + sy*sy; length > 0 {
+		u = math.Max(0, math.Min(1, ((x-x1)*sx+(y-y1)*sy)/length))
+	}
+	return distanceBetweenPoints(x, y, x1+u*sx, y1+u*sy)
+}
@@ -0,0 +1,51 @@
+package raycaster
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSegmentIntersect(t *testing.T) {
+	diagonal := Segment{0, 0, 1, 1}
+
+	// looking right from the middle of the tile to the left of the diagonal in tile (1, 1)
+	hit, ok := diagonal.intersect(1, 1, TileSize/2, 1.5*TileSize, 0)
+	if !ok {
+		t.Fatal("Ray should hit the diagonal")
+	}
+	if hit.x != 1.5*TileSize || hit.distance != TileSize || hit.face != faceNone {
+		t.Errorf("Should hit the middle of the diagonal. Received: %+v", hit)
+	}
+	// the diagonal is longer than a tile so the texture repeats before the middle
+	if want := math.Mod(math.Sqrt2*TileSize/2, TileSize); math.Abs(hit.offset-want) > 1e-9 {
+		t.Errorf("Expected texture offset %f. Received: %f", want, hit.offset)
+	}
+
+	if _, ok := diagonal.intersect(1, 1, TileSize/2, 1.5*TileSize, PI); ok {
+		t.Error("Ray going the other way should not hit the diagonal")
+	}
+	if _, ok := diagonal.intersect(1, 1, TileSize/2, 2.5*TileSize, 0); ok {
+		t.Error("Ray going past the end of the diagonal should not hit it")
+	}
+}
+
+func TestSegmentCollision(t *testing.T) {
+	l := &Level{
+		Data: LevelData{
+			{0, 0, 0},
+			{0, 2, 0},
+			{0, 0, 0},
+		},
+		Tiles: map[int]*Tile{
+			2: {Type: TileSegment, Segments: []Segment{{0.5, 0, 0.5, 1}}},
+		},
+	}
+	gm := NewGameMap(l)
+
+	if !gm.HasWallAt(1.5*TileSize, 1.5*TileSize) {
+		t.Error("Player should not be able to stand on the segment")
+	}
+	if gm.HasWallAt(1.2*TileSize, 1.5*TileSize) {
+		t.Error("The rest of the segment tile should be empty")
+	}
+}
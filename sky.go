@@ -0,0 +1,32 @@
+package raycaster
+
+import (
+	"image"
+	"math"
+)
+
+// skyColumn returns the column of the sky texture for the ray angle. The sky wraps all the way
+// around the player so the same angle always shows the same part of the sky.
+func skyColumn(sky *image.NRGBA, angle float64) int {
+	column := int(normalizeAngle(angle) / TwoPI * float64(sky.Bounds().Dx()))
+	if column >= sky.Bounds().Dx() { // angle is exactly 2*PI
+		column = 0
+	}
+	return column
+}
+
+// copySkyTexel copies the texel of the sky for the row straight into the color buffer.
+// The sky texture is stretched to skyPanoramaWidth, how wide the screen would have to be to show the
+// whole 360 degrees around the player, so it moves at the same speed as the walls when turning.
+// The bottom of the sky sits on the horizon so it moves when looking up and down. It is scaled
+// by the same amount as the width so it isn't squashed. Anything above the sky uses its top row.
+// The sky is infinitely far away so there's no fog.
+func (e *Engine) copySkyTexel(column, row int, sky *image.NRGBA, skyX int) {
+	scale := float64(sky.Bounds().Dx()) / e.skyPanoramaWidth * e.distanceToProjPlane / e.rowsToProjPlane
+	skyY := sky.Bounds().Dy() - 1 - int(float64(e.horizon-row)*scale)
+	skyY = int(math.Max(0, float64(skyY)))
+
+	src := sky.PixOffset(skyX, skyY)
+	dst := e.Frame.PixelOffset(column, row)
+	copy(e.Frame.Pixels[dst:dst+4], sky.Pix[src:src+4])
+}
@@ -0,0 +1,26 @@
+package raycaster
+
+import (
+	"image"
+	"testing"
+)
+
+func TestSkyColumnWrapsAround(t *testing.T) {
+	sky := image.NewNRGBA(image.Rect(0, 0, 360, 10))
+
+	cases := []struct {
+		angle  float64
+		column int
+	}{
+		{0, 0},
+		{PI, 180},
+		{TwoPI, 0},
+		{-PI / 2, 270},
+		{TwoPI + PI/2, 90},
+	}
+	for _, c := range cases {
+		if column := skyColumn(sky, c.angle); column != c.column {
+			t.Errorf("Angle %f should use column %d. Received: %d", c.angle, c.column, column)
+		}
+	}
+}
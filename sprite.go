@@ -0,0 +1,170 @@
+package raycaster
+
+import (
+	"image"
+	"math"
+)
+
+// Sprite is an object standing in the world. It's always drawn facing the camera (billboard)
+// and has the same height as a wall at the same distance so it sits on the floor.
+type Sprite struct {
+	X       float64 `json:"x"` // world coordinates (not tiles)
+	Y       float64 `json:"y"`
+	Texture string  `json:"texture"` // name of the image in the images directory
+
+	distance    float64        // distance to the player. Updated every frame before sorting.
+	angle       float64        // angle relative to the direction the player is looking at
+	seenThrough *viewTransform // the mirrors and portals it's seen through or nil for the sprite itself
+}
+
+// renderSprites draws all the sprites of the level from the furthest to the closest
+// so the closer ones are drawn on top. The ones seen in mirrors and portals are drawn
+// first since they are always behind them.
+func (e *Engine) renderSprites() {
+	sprites := e.GameMap.Level.Sprites
+
+	views := e.findViews()
+	e.seenThrough = e.seenThrough[:0]
+	for v := range views {
+		for _, s := range sprites {
+			c := *s
+			x, y := views[v].apply(s.X, s.Y)
+			c.place(e.Player, x, y)
+			c.seenThrough = &views[v]
+			e.seenThrough = append(e.seenThrough, c)
+		}
+	}
+	// the copies don't move anymore so we can point at them
+	e.seenOrder = e.seenOrder[:0]
+	for i := range e.seenThrough {
+		e.seenOrder = append(e.seenOrder, &e.seenThrough[i])
+	}
+	e.drawSprites(e.seenOrder)
+
+	for _, s := range sprites {
+		s.place(e.Player, s.X, s.Y)
+	}
+	e.drawSprites(sprites)
+}
+
+// drawSprites sorts the sprites from the furthest and draws them
+func (e *Engine) drawSprites(sprites []*Sprite) {
+	sortSprites(sprites)
+
+	for _, s := range sprites {
+		e.renderSprite(s, e.GameMap.Level.texture(s.Texture))
+	}
+}
+
+// sortSprites sorts the sprites from the furthest to the closest. The sprites hardly move from one
+// frame to the next so they are almost always sorted already which is what an insertion sort is good at.
+// It doesn't allocate anything either unlike sort.Slice.
+func sortSprites(sprites []*Sprite) {
+	for i := 1; i < len(sprites); i++ {
+		for j := i; j > 0 && sprites[j].distance > sprites[j-1].distance; j-- {
+			sprites[j], sprites[j-1] = sprites[j-1], sprites[j]
+		}
+	}
+}
+
+// place works out the distance and angle from the player to the sprite as if it was at x, y.
+// Sprites seen in mirrors and portals look like they are somewhere else.
+func (s *Sprite) place(p *Player, x, y float64) {
+	dx, dy := x-p.x, y-p.y
+	s.distance = math.Sqrt(dx*dx + dy*dy)
+	s.angle = normalizeAngle(math.Atan2(dy, dx) - p.rotationAngle)
+	if s.angle > PI { // keep it between -PI and PI so we know which side of the center it's on
+		s.angle -= TwoPI
+	}
+}
+
+func (e *Engine) renderSprite(s *Sprite, texture *image.NRGBA) {
+	// same as the walls, use the perpendicular distance so we don't get the fisheye effect
+	perpendicularDistance := s.distance * math.Cos(s.angle)
+	if perpendicularDistance < 1 { // behind the player or too close to see
+		return
+	}
+
+	// the sprite takes up a tile so it has the same height as a wall at the same distance
+	spriteHeight := (TileSize / perpendicularDistance) * e.rowsToProjPlane
+	bounds := texture.Bounds()
+	spriteWidth := (TileSize / perpendicularDistance) * e.distanceToProjPlane * float64(bounds.Dx()) / float64(bounds.Dy())
+
+	// where on the screen the center of the sprite is
+	spriteCenterX := math.Tan(s.angle)*e.distanceToProjPlane + float64(e.Frame.Width)/2
+
+	spriteLeft := spriteCenterX - spriteWidth/2
+	// sprites stand on the floor like the walls
+	_, spriteBottom := e.wallStrip(perpendicularDistance, 1)
+	spriteTop := spriteBottom - spriteHeight
+
+	startX := int(math.Max(spriteLeft, 0))
+	endX := int(math.Min(spriteLeft+spriteWidth, float64(len(e.Rays))))
+	startY := int(math.Max(spriteTop, 0))
+	endY := int(math.Min(spriteTop+spriteHeight, float64(e.Frame.Height)))
+
+	fog := &e.GameMap.Level.Fog
+	if e.sampling == SamplingMipmap {
+		texture = e.mipLevel(texture, spriteWidth, spriteHeight)
+	}
+
+	fogWeight := fog.weight(perpendicularDistance)
+	// the whole sprite gets the light of the floor it's standing on
+	light := e.GameMap.FloorLight(s.X, s.Y)
+
+	for x := startX; x < endX; x++ {
+		e.drawWallsBehind(x, perpendicularDistance)
+
+		// there's a wall in front of the sprite in this column so only the part above it is visible
+		columnStartY, columnEndY, leg := startY, endY, 0
+		if s.seenThrough != nil {
+			var ok bool
+			if columnStartY, columnEndY, leg, ok = e.visibleRows(*s.seenThrough, x, perpendicularDistance, startY, endY); !ok {
+				continue
+			}
+		} else if e.zBuffer[x] < perpendicularDistance {
+			columnEndY = e.visibleRowsAbove(x, perpendicularDistance, endY)
+		}
+
+		u := (float64(x) + 0.5 - spriteLeft) / spriteWidth
+		for y := columnStartY; y < columnEndY; y++ {
+			v := (float64(y) + 0.5 - spriteTop) / spriteHeight
+
+			texel := sampleTexture(texture, u, v, e.sampling, false)
+			if texel.A == 0 { // transparent so we leave whatever is behind
+				continue
+			}
+			e.Frame.Set(x, y, nrgbaToUint32(texel))
+			lightPixel(e.Frame, x, y, light)
+			fogPixel(e.Frame, x, y, fog.Color, fogWeight)
+			if leg > 0 {
+				e.tintPixel(x, y, e.Rays[x], leg)
+			}
+		}
+	}
+}
+
+// visibleRowsAbove returns the first row in the column that is hidden by a wall closer than the
+// distance. Walls start at the floor so anything below the top of a closer wall is hidden.
+// See-through walls don't hide anything. The ones in front of the sprite are drawn on top of it
+// after it (see drawWallsBehind).
+func (e *Engine) visibleRowsAbove(column int, perpendicularDistance float64, endY int) int {
+	ray := e.Rays[column]
+	cosRelative := math.Cos(ray.angle - e.Player.rotationAngle)
+
+	for i := range ray.hits {
+		hit := &ray.hits[i]
+		wallDistance := hit.distance * cosRelative
+		if wallDistance >= perpendicularDistance {
+			break // sorted from the closest so the rest are behind the sprite
+		}
+		if e.GameMap.IsTransparent(hit.content) {
+			continue
+		}
+		top, _ := e.wallStrip(wallDistance, e.GameMap.Level.WallHeight(hit.content))
+		if row := e.clampRow(top); row < endY {
+			endY = row
+		}
+	}
+	return endY
+}
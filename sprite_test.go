@@ -0,0 +1,107 @@
+package raycaster
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSortSprites(t *testing.T) {
+	tests := []struct {
+		distances []float64
+		expected  []float64
+	}{
+		{[]float64{}, []float64{}},
+		{[]float64{3, 2, 1}, []float64{3, 2, 1}}, // already sorted
+		{[]float64{1, 2, 3}, []float64{3, 2, 1}},
+		{[]float64{2, 5, 1, 5, 3}, []float64{5, 5, 3, 2, 1}},
+	}
+
+	for _, test := range tests {
+		sprites := make([]*Sprite, len(test.distances))
+		for i, d := range test.distances {
+			sprites[i] = &Sprite{distance: d}
+		}
+		sortSprites(sprites)
+		for i, s := range sprites {
+			if s.distance != test.expected[i] {
+				t.Errorf("sortSprites(%v): Expected %v. Received: %f at %d", test.distances, test.expected, s.distance, i)
+				break
+			}
+		}
+	}
+}
+
+func TestRenderSprite(t *testing.T) {
+	const (
+		green = 0x00FF00FF // the walls
+		red   = 0xFF0000FF // the sprite
+	)
+	tests := []struct {
+		name     string
+		wall     int     // the tile in the middle of the corridor between the player and the far wall
+		x        float64 // where the sprite is along the corridor
+		texture  string
+		expected uint32 // the pixel in the middle of the screen
+	}{
+		{"in the open", 0, 4.5 * TileSize, "red", red},
+		{"in front of a wall", 1, 2.5 * TileSize, "red", red},
+		{"behind a closer wall", 1, 4.5 * TileSize, "red", green},
+		{"transparent texels", 0, 4.5 * TileSize, "clear", green},
+	}
+
+	e, err := New(Config{Width: 64, Height: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		e.GameMap = NewGameMap(&Level{
+			Data: LevelData{
+				{1, 1, 1, 1, 1, 1, 1},
+				{1, 0, 0, test.wall, 0, 0, 1},
+				{1, 1, 1, 1, 1, 1, 1},
+			},
+			Textures: map[int]string{1: "wall"},
+			Sprites:  []*Sprite{{X: test.x, Y: 1.5 * TileSize, Texture: test.texture}},
+			textures: map[string]*image.NRGBA{
+				"wall":  filled(color.NRGBA{0, 255, 0, 255}),
+				"red":   filled(color.NRGBA{255, 0, 0, 255}),
+				"clear": filled(color.NRGBA{255, 0, 0, 0}),
+			},
+		})
+		e.Player.Place(1.5*TileSize, 1.5*TileSize, 0)
+		e.Update(0)
+		e.Render()
+
+		if c := e.Frame.At(32, 20); c != test.expected {
+			t.Errorf("%s: Expected %08x. Received: %08x", test.name, test.expected, c)
+		}
+	}
+}
+
+func TestRenderSpritesDoesntAllocate(t *testing.T) {
+	e, err := New(Config{Width: 64, Height: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the sprite is seen in the mirror too
+	e.GameMap = NewGameMap(&Level{
+		Data: LevelData{
+			{1, 1, 1, 1, 1, 1},
+			{1, 0, 0, 0, 0, 2},
+			{1, 1, 1, 1, 1, 1},
+		},
+		Tiles:   map[int]*Tile{2: {Type: TileMirror}},
+		Sprites: []*Sprite{{X: 2.5 * TileSize, Y: 1.5 * TileSize}, {X: 3.5 * TileSize, Y: 1.5 * TileSize}},
+	})
+	e.Player.Place(1.5*TileSize, 1.5*TileSize, 0)
+	e.Update(0)
+	e.renderSprites()
+	if len(e.seenThrough) != 2 {
+		t.Fatalf("Expected both sprites in the mirror. Received: %d", len(e.seenThrough))
+	}
+
+	if allocs := testing.AllocsPerRun(10, e.renderSprites); allocs != 0 {
+		t.Errorf("Drawing the sprites should not allocate. Received: %f allocations", allocs)
+	}
+}
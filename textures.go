@@ -0,0 +1,110 @@
+package raycaster
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// placeholderTexture is used for any tile ID that doesn't map to a loaded texture.
+// It's a magenta/black checkerboard so missing textures are easy to spot in game.
+var placeholderTexture = newPlaceholderTexture(64, 64)
+
+func newPlaceholderTexture(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	magenta := color.NRGBA{R: 255, G: 0, B: 255, A: 255}
+	black := color.NRGBA{R: 0, G: 0, B: 0, A: 255}
+
+	const checker = 8 // size of each square in the checkerboard
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if (x/checker+y/checker)%2 == 0 {
+				img.SetNRGBA(x, y, magenta)
+			} else {
+				img.SetNRGBA(x, y, black)
+			}
+		}
+	}
+	return img
+}
+
+// textureByName returns the loaded texture or the placeholder if there isn't one with that name.
+func textureByName(textures map[string]*image.NRGBA, name string) *image.NRGBA {
+	if tex, ok := textures[name]; ok {
+		return tex
+	}
+	return placeholderTexture
+}
+
+// loadTextures loads every image in the directory with its mip levels and sets up the animations
+func (e *Engine) loadTextures(imageDir string) error {
+	files, err := ioutil.ReadDir(imageDir)
+	if err != nil {
+		return err
+	}
+
+	e.textures = make(map[string]*image.NRGBA, len(files))
+	e.mipmaps = make(map[*image.NRGBA][]*image.NRGBA, len(files))
+	var descriptors []string // animation descriptors. They need all the images to be loaded first.
+	for _, file := range files {
+		filename := file.Name()
+		if path.Ext(filename) == ".json" {
+			descriptors = append(descriptors, strings.TrimSuffix(filename, ".json"))
+			continue
+		}
+
+		imgNRGBA, err := loadImage(path.Join(imageDir, filename))
+		if err != nil {
+			return err
+		}
+
+		e.textures[strings.TrimSuffix(filename, path.Ext(filename))] = imgNRGBA
+		e.mipmaps[imgNRGBA] = generateMipmaps(imgNRGBA)
+	}
+
+	return e.loadAnimations(imageDir, descriptors)
+}
+
+func loadImage(filename string) (*image.NRGBA, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file: %s", err)
+	}
+	defer f.Close()
+
+	img, err := decodeImage(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode image from file: %s. Error: %s", filename, err)
+	}
+	return img, nil
+}
+
+func decodeImage(r io.Reader) (*image.NRGBA, error) {
+	img, err := png.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var imgNRGBA *image.NRGBA
+	var ok bool
+	if imgNRGBA, ok = img.(*image.NRGBA); !ok {
+		switch img.ColorModel() {
+		case color.RGBAModel:
+			imgNRGBA = image.NewNRGBA(img.Bounds())
+			draw.Draw(imgNRGBA, img.Bounds(), img, image.Point{}, draw.Src)
+		case color.GrayModel, color.Gray16Model, color.AlphaModel, color.Alpha16Model:
+			fallthrough
+		default:
+			return nil, errors.New("unsupported image format")
+		}
+	}
+	return imgNRGBA, nil
+}
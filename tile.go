@@ -0,0 +1,97 @@
+package raycaster
+
+import "fmt"
+
+// Tile types
+const (
+	TileWall     = "wall"
+	TileDoor     = "door"
+	TilePushwall = "pushwall" // a secret wall that moves when the player uses it
+	TileSegment  = "segment"  // walls that are lines inside the tile instead of the whole tile e.g. diagonals
+	TileMirror   = "mirror"   // reflects whatever is in front of it
+)
+
+// Tile describes how a tile ID from the map behaves. Any ID that isn't
+// in the level's tile table is a plain wall.
+type Tile struct {
+	Type string `json:"type"`
+
+	// Frame is the texture used on the walls on either side of a door
+	Frame string `json:"frame"`
+
+	// Height of the wall in tiles e.g. 0.5 for a half wall or 2 for a tower. Defaults to 1.
+	Height float64 `json:"height"`
+
+	// Transparent tiles use the alpha of their texture e.g. grates, fences or stained glass.
+	// Rays keep going after hitting them so whatever is behind can be seen.
+	Transparent bool `json:"transparent"`
+
+	// Passable tiles don't stop the player from walking through them e.g. cobwebs or curtains
+	Passable bool `json:"passable"`
+
+	// Segments are the walls inside a segment tile. Everything else in the tile is empty.
+	Segments []Segment `json:"segments"`
+
+	// Tint is blended on top of the reflection of a mirror using its alpha e.g. "#a0c0ff40".
+	// Mirrors hide everything behind them so they should be as tall as the walls around them.
+	Tint HexColor `json:"tint"`
+
+	// Faces sets a different texture for some of the faces of the tile by face e.g. "north": "redbrick".
+	// The other faces use the texture of the tile ID.
+	Faces map[string]string `json:"faces"`
+}
+
+// defaultTile is used for every ID that doesn't have a tile definition
+var defaultTile = &Tile{Type: TileWall}
+
+// TileFace is a face of a wall tile on the map e.g. one end of a portal or where a decal is
+type TileFace struct {
+	Row  int    `json:"row"`
+	Col  int    `json:"col"`
+	Face string `json:"face"` // the side of the tile: north, south, west or east
+}
+
+// faceNames maps the faces in the level file to faces
+var faceNames = map[string]int{
+	"north": faceNorth,
+	"south": faceSouth,
+	"west":  faceWest,
+	"east":  faceEast,
+}
+
+// faceName returns the name of the face in the level file
+func faceName(face int) string {
+	for name, f := range faceNames {
+		if f == face {
+			return name
+		}
+	}
+	return ""
+}
+
+// faceKey is a face of a tile
+type faceKey struct {
+	i, j, face int
+}
+
+// key returns the face of the tile or an error if it isn't a wall face on the map
+func (f TileFace) key(l *Level) (faceKey, error) {
+	face, ok := faceNames[f.Face]
+	if !ok {
+		return faceKey{}, fmt.Errorf("unknown face %q. Use north, south, west or east", f.Face)
+	}
+	if f.Row < 0 || f.Row >= len(l.Data) || f.Col < 0 || f.Col >= len(l.Data[f.Row]) {
+		return faceKey{}, fmt.Errorf("tile %d, %d is outside of the map", f.Row, f.Col)
+	}
+	if l.At(f.Row, f.Col) == 0 {
+		return faceKey{}, fmt.Errorf("tile %d, %d is not a wall", f.Row, f.Col)
+	}
+	return faceKey{f.Row, f.Col, face}, nil
+}
+
+// center returns the point in the middle of the face
+func (k faceKey) center() (x, y float64) {
+	x, y = (float64(k.j)+0.5)*TileSize, (float64(k.i)+0.5)*TileSize
+	normal := faceNormals[k.face]
+	return x + normal[0]*TileSize/2, y + normal[1]*TileSize/2
+}
@@ -1,8 +1,12 @@
-package main
+package raycaster
 
 import (
+	"encoding/json"
+	"fmt"
 	"image/color"
 	"math"
+	"strconv"
+	"strings"
 )
 
 func distanceBetweenPoints(x1, y1, x2, y2 float64) float64 {
@@ -19,30 +23,33 @@ func normalizeAngle(angle float64) float64 {
 	return rAngle
 }
 
-// Get back values we can use with Go SDL2
-func minimapScale(val interface{}) int32 {
-	v := 0.0
-	switch val.(type) {
-	case int8, int32, int64:
-		v = float64(val.(int))
-	case int:
-		v = float64(val.(int))
-	default:
-		v = val.(float64)
+func abs(n int) int {
+	if n < 0 {
+		return -n
 	}
+	return n
+}
 
-	return int32(MinimapScaleFactor * v)
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
 }
 
-// Convert from Uint32 to RGBA color values
-func uint32ToColorRGBA(h uint32) color.RGBA {
-	return color.RGBA{
-		R: uint8(h >> 24),
-		G: uint8(h >> 16),
-		B: uint8(h >> 8),
-		A: uint8(h),
+func minInt(a, b int) int {
+	if a < b {
+		return a
 	}
+	return b
+}
+
+// Scale a position on the map down to window pixels on the minimap
+func minimapScale(scale, v float64) int {
+	return int(scale * v)
 }
+
+// Convert from Uint32 to NRGBA color values
 func uint32ToColorNRGBA(h uint32) color.NRGBA {
 	return color.NRGBA{
 		R: uint8(h >> 24),
@@ -52,37 +59,35 @@ func uint32ToColorNRGBA(h uint32) color.NRGBA {
 	}
 }
 
-// func clamp()
+// Convert from NRGBA color values to Uint32 (the format the color buffer uses)
+func nrgbaToUint32(c color.NRGBA) uint32 {
+	return uint32(c.R)<<24 | uint32(c.G)<<16 | uint32(c.B)<<8 | uint32(c.A)
+}
+
+// HexColor is a color that's written as a hex string in the level files.
+// Either "#RRGGBB" or "#RRGGBBAA" (alpha defaults to FF)
+type HexColor color.NRGBA
 
-/*
-	Calculating the pitch
-	[https://stackoverflow.com/questions/37643392/getting-all-pixel-valuesrgba]
-
-	RGBA struct in Go:
-	type RGBA struct {
-		// Pix holds the image's pixels, in R, G, B, A order. The pixel at
-		// (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*4].
-		Pix []uint8
-		// Stride is the Pix stride (in bytes) between vertically adjacent pixels.
-		Stride int
-		// Rect is the image's bounds.
-		Rect Rectangle
+func (c *HexColor) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 6 {
+		hex += "FF"
+	}
+	if len(hex) != 8 {
+		return fmt.Errorf("invalid color %q. Expected #RRGGBB or #RRGGBBAA", s)
 	}
-	Since Pix holds the individual pixels in a uint8 array, the pitch as
-	defined in the SDL documentation [https://wiki.libsdl.org/SDL\_UpdateTexture]:
-	`pitch: the number of bytes in a row of pixel data, including padding between lines`
-
-	The number of bytes in a row of pixel data for each row for us is `WindowWidth * 4`.
-	Since every pixel (well rgba) is 4 bytes so each row has WindowWidth length and each RGBA value
-	for the single pixel will be 4 bytes long.
-
-	Long description since this tripped my up a few times so i am making a lengthy note here.
-
-	UPDATE: and i just did not simply see there is a Stride which is the pitch basically and we can use
-			that instead! :) ooops
-*/
-func calculatePitch() int {
-	// var a uint32
-	// pitch := int(WindowWidth * unsafe.Sizeof(a))
-	return WindowWidth * 4
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return fmt.Errorf("invalid color %q: %s", s, err)
+	}
+
+	*c = HexColor(uint32ToColorNRGBA(uint32(v)))
+	return nil
 }
+
+// func clamp()
@@ -0,0 +1,131 @@
+package raycaster
+
+import "math"
+
+// viewTransform moves a point in the world to where it's seen from the player after the ray went
+// through mirrors and portals: x' = xx*x + xy*y + dx and y' = yx*x + yy*y + dy.
+// Mirrors and portals are always on grid lines and at right angles so the numbers are exact.
+type viewTransform struct {
+	xx, xy, yx, yy float64
+	dx, dy         float64
+}
+
+// identityView leaves everything where it is. It's the view of the first leg of every ray.
+var identityView = viewTransform{xx: 1, yy: 1}
+
+// apply returns where the point x, y is seen
+func (t viewTransform) apply(x, y float64) (float64, float64) {
+	return t.xx*x + t.xy*y + t.dx, t.yx*x + t.yy*y + t.dy
+}
+
+// after returns the transform that applies o first and then t
+func (t viewTransform) after(o viewTransform) viewTransform {
+	return viewTransform{
+		xx: t.xx*o.xx + t.xy*o.yx,
+		xy: t.xx*o.xy + t.xy*o.yy,
+		yx: t.yx*o.xx + t.yy*o.yx,
+		yy: t.yx*o.xy + t.yy*o.yy,
+		dx: t.xx*o.dx + t.xy*o.dy + t.dx,
+		dy: t.yx*o.dx + t.yy*o.dy + t.dy,
+	}
+}
+
+// inverse returns the transform that undoes t. It only rotates or flips so we can just transpose it.
+func (t viewTransform) inverse() viewTransform {
+	return viewTransform{
+		xx: t.xx,
+		xy: t.yx,
+		yx: t.xy,
+		yy: t.yy,
+		dx: -(t.xx*t.dx + t.yx*t.dy),
+		dy: -(t.xy*t.dx + t.yy*t.dy),
+	}
+}
+
+// findViews collects every view the rays saw through mirrors and portals this frame.
+// Every one of them shows a copy of the sprites.
+func (e *Engine) findViews() []viewTransform {
+	views := e.views[:0]
+	for _, ray := range e.Rays {
+	legs:
+		for _, leg := range ray.legs[1:] {
+			for _, seen := range views {
+				if seen == leg.view {
+					continue legs
+				}
+			}
+			views = append(views, leg.view)
+		}
+	}
+	e.views = views
+	return views
+}
+
+// legWithView returns the first leg of the ray that has the view or 0 if none of them do
+func (r *Ray) legWithView(view viewTransform) int {
+	for l := 1; l < len(r.legs); l++ {
+		if r.legs[l].view == view {
+			return l
+		}
+	}
+	return 0
+}
+
+// isLegStart returns true if the hit is one of the mirrors or portals the ray went through to get to the leg
+func (r *Ray) isLegStart(hit, leg int) bool {
+	for l := 1; l <= leg; l++ {
+		if r.legs[l].from == hit {
+			return true
+		}
+	}
+	return false
+}
+
+// visibleRows returns the rows of the column where a sprite seen through the view at the distance is
+// visible and the leg of the ray it is seen in. It's only visible inside the mirrors and portals the ray went
+// through and in front of anything else the ray hit on the way there. Returns false if the ray doesn't have the view.
+func (e *Engine) visibleRows(view viewTransform, column int, perpendicularDistance float64, startY, endY int) (int, int, int, bool) {
+	ray := e.Rays[column]
+	leg := ray.legWithView(view)
+	if leg == 0 {
+		return 0, 0, 0, false
+	}
+	cosRelative := math.Cos(ray.angle - e.Player.rotationAngle)
+	if ray.legs[leg].start*cosRelative >= perpendicularDistance {
+		return 0, 0, 0, false // it's in front of the mirror so it's not in it
+	}
+	if leg+1 < len(ray.legs) && ray.legs[leg+1].start*cosRelative <= perpendicularDistance {
+		return 0, 0, 0, false // behind the next mirror
+	}
+
+	for i := range ray.hits {
+		hit := &ray.hits[i]
+		wallDistance := hit.distance * cosRelative
+		if wallDistance >= perpendicularDistance {
+			break
+		}
+		if e.GameMap.IsTransparent(hit.content) {
+			continue
+		}
+		top, bottom := e.wallStrip(wallDistance, e.GameMap.Level.WallHeight(hit.content))
+		if ray.isLegStart(i, leg) { // we can only see it inside the mirror
+			startY = maxInt(startY, e.clampRow(top))
+			endY = minInt(endY, e.clampRow(bottom))
+			continue
+		}
+		endY = minInt(endY, e.clampRow(top))
+	}
+	return startY, endY, leg, startY < endY
+}
+
+// throughRows keeps the rows from top to bottom inside the mirrors and portals the ray went
+// through to get to the distance along the ray
+func (e *Engine) throughRows(ray *Ray, distance, cosRelative float64, top, bottom int) (int, int) {
+	for l := 1; l < len(ray.legs) && ray.legs[l].start <= distance; l++ {
+		hit := &ray.hits[ray.legs[l].from]
+		throughTop, throughBottom := e.wallStrip(hit.distance*cosRelative, e.GameMap.Level.WallHeight(hit.content))
+		top = maxInt(top, e.clampRow(throughTop))
+		bottom = minInt(bottom, e.clampRow(throughBottom))
+	}
+	return top, bottom
+}